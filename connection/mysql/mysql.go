@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"fmt"
 	"github.com/kelseyhightower/envconfig"
 	"gorm.io/driver/mysql"
@@ -28,6 +29,7 @@ type config struct {
 	MaxOpenConns    int    `envconfig:"MYSQL_MAX_OPEN_CONNS" default:"100"`
 	MaxIdleConns    int    `envconfig:"MYSQL_MAX_IDLE_CONNS" default:"10"`
 	ConnMaxLifetime int    `envconfig:"MYSQL_CONN_MAX_LIFETIME" default:"10"`
+	Tracing         bool   `envconfig:"MYSQL_TRACING" default:"false"`
 
 	db *gorm.DB
 }
@@ -58,6 +60,13 @@ func (c *config) connect() *gorm.DB {
 		return nil
 	}
 
+	if c.Tracing {
+		if err = dbCon.Use(TracingPlugin()); err != nil {
+			gologger.Fatalf("failed register tracing plugin to database: %v", err)
+			return nil
+		}
+	}
+
 	sqlDB, _ := dbCon.DB()
 	sqlDB.SetMaxIdleConns(c.MaxIdleConns)
 	sqlDB.SetMaxOpenConns(c.MaxOpenConns)
@@ -99,3 +108,26 @@ func Close(db *gorm.DB) {
 		gologger.Fatalf("failed close connection database: %v", err)
 	}
 }
+
+// HealthCheck returns a probe that pings the underlying sql.DB of db.
+func HealthCheck(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// ShutdownHook returns a hook that closes the underlying sql.DB of db,
+// suitable for registering as a service shutdown hook.
+func ShutdownHook(db *gorm.DB) func(ctx context.Context) error {
+	return func(_ context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	}
+}
@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"errors"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	gotrace "pkg.tanyudii.me/go-pkg/go-trace"
+)
+
+const (
+	tracingPluginName = "gotrace"
+	tracingSpanKey    = "gotrace:span"
+)
+
+type tracingPlugin struct{}
+
+// TracingPlugin starts a client span for every gorm query, child of the
+// span in the statement context, e.g. db.WithContext(ctx).
+func TracingPlugin() gorm.Plugin {
+	return &tracingPlugin{}
+}
+
+func (p *tracingPlugin) Name() string {
+	return tracingPluginName
+}
+
+func (p *tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("gotrace:before_create", p.before("gorm.Create")),
+		cb.Create().After("gorm:create").Register("gotrace:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("gotrace:before_query", p.before("gorm.Query")),
+		cb.Query().After("gorm:query").Register("gotrace:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("gotrace:before_update", p.before("gorm.Update")),
+		cb.Update().After("gorm:update").Register("gotrace:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("gotrace:before_delete", p.before("gorm.Delete")),
+		cb.Delete().After("gorm:delete").Register("gotrace:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("gotrace:before_row", p.before("gorm.Row")),
+		cb.Row().After("gorm:row").Register("gotrace:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("gotrace:before_raw", p.before("gorm.Raw")),
+		cb.Raw().After("gorm:raw").Register("gotrace:after_raw", p.after),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *tracingPlugin) before(name string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+		ctx, span := gotrace.Start(
+			db.Statement.Context,
+			name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemMySQL),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func (p *tracingPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(
+		semconv.DBStatement(db.Statement.SQL.String()),
+		semconv.DBSQLTable(db.Statement.Table),
+	)
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	gotrace.End(span, err)
+}
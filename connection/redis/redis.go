@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"github.com/kelseyhightower/envconfig"
@@ -14,6 +15,7 @@ type config struct {
 	Host     string `envconfig:"REDIS_HOST" required:"127.0.0.1"`
 	Port     string `envconfig:"REDIS_PORT" required:"6370"`
 	Network  string `envconfig:"REDIS_NETWORK" default:"tcp"`
+	Tracing  bool   `envconfig:"REDIS_TRACING" default:"false"`
 
 	client *redis.Client
 }
@@ -27,6 +29,9 @@ func (c *config) connect() *redis.Client {
 		Username: c.Username,
 		Password: c.Password,
 	})
+	if c.Tracing {
+		c.client.AddHook(TracingHook())
+	}
 	return c.client
 }
 
@@ -52,3 +57,18 @@ func Close(cli *redis.Client) {
 		gologger.Fatalf("failed close connection redis: %v", err)
 	}
 }
+
+// HealthCheck returns a probe that sends PING to cli.
+func HealthCheck(cli *redis.Client) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return cli.Ping(ctx).Err()
+	}
+}
+
+// ShutdownHook returns a hook that closes cli, suitable for registering as a
+// service shutdown hook.
+func ShutdownHook(cli *redis.Client) func(ctx context.Context) error {
+	return func(_ context.Context) error {
+		return cli.Close()
+	}
+}
@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	gotrace "pkg.tanyudii.me/go-pkg/go-trace"
+)
+
+type tracingSpanKey struct{}
+
+type tracingHook struct{}
+
+// TracingHook starts a client span for every command and pipeline, child of
+// the span in the command context.
+func TracingHook() redis.Hook {
+	return &tracingHook{}
+}
+
+func (h *tracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.start(ctx, cmd.FullName()), nil
+}
+
+func (h *tracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.end(ctx, cmd.Err())
+	return nil
+}
+
+func (h *tracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	ctx = h.start(ctx, "pipeline")
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("db.redis.pipeline_length", len(cmds)))
+	return ctx, nil
+}
+
+func (h *tracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+			err = cmdErr
+			break
+		}
+	}
+	h.end(ctx, err)
+	return nil
+}
+
+func (h *tracingHook) start(ctx context.Context, name string) context.Context {
+	ctx, span := gotrace.Start(
+		ctx,
+		"redis."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperation(name)),
+	)
+	return context.WithValue(ctx, tracingSpanKey{}, span)
+}
+
+func (h *tracingHook) end(ctx context.Context, err error) {
+	span, ok := ctx.Value(tracingSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	gotrace.End(span, err)
+}
@@ -0,0 +1,71 @@
+package go_audit
+
+import (
+	"strings"
+)
+
+var (
+	// DefaultRedactFields are left out of the payload digest.
+	DefaultRedactFields = []string{"password", "token", "secret", "authorization"}
+)
+
+type Config struct {
+	sinks        []Sink
+	methods      []string
+	skipMethods  []string
+	redactFields map[string]bool
+}
+
+type ConfigFunc func(c *Config)
+
+func WithSink(s ...Sink) ConfigFunc {
+	return func(c *Config) {
+		c.sinks = append(c.sinks, s...)
+	}
+}
+
+// Methods audits only the methods matching one of the patterns, every method
+// when none is given. A pattern ending with * matches by prefix, e.g.
+// "/order.Service/Create*".
+func Methods(patterns ...string) ConfigFunc {
+	return func(c *Config) {
+		c.methods = append(c.methods, patterns...)
+	}
+}
+
+// SkipMethods never audits the methods matching one of the patterns.
+func SkipMethods(patterns ...string) ConfigFunc {
+	return func(c *Config) {
+		c.skipMethods = append(c.skipMethods, patterns...)
+	}
+}
+
+// RedactFields adds payload fields, by JSON or proto name at any depth, that
+// are left out of the payload digest.
+func RedactFields(fields ...string) ConfigFunc {
+	return func(c *Config) {
+		for _, f := range fields {
+			c.redactFields[strings.ToLower(f)] = true
+		}
+	}
+}
+
+func generate(args ...ConfigFunc) *Config {
+	c := &Config{
+		redactFields: make(map[string]bool),
+	}
+	for _, f := range DefaultRedactFields {
+		c.redactFields[f] = true
+	}
+	for i := range args {
+		args[i](c)
+	}
+	if len(c.sinks) == 0 {
+		c.sinks = append(c.sinks, NewLoggerSink())
+	}
+
+	// never audit grpc health check
+	c.skipMethods = append(c.skipMethods, "/grpc.health.v1.Health/*")
+
+	return c
+}
@@ -0,0 +1,40 @@
+package grpc_audit
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	goaudit "pkg.tanyudii.me/go-pkg/go-audit"
+	gogrpc "pkg.tanyudii.me/go-pkg/go-grpc"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"time"
+)
+
+// UnaryInterceptor audits the matched methods. It can run before or after
+// auth, the actor is the identity auth verifies during the call.
+func UnaryInterceptor(svc goaudit.Service) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !svc.Match(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		start := time.Now()
+		ctx = gotex.WithVerifiedIdentity(ctx)
+		resp, err := handler(ctx, req)
+		svc.Record(ctx, info.FullMethod, req, start, err)
+		return resp, err
+	}
+}
+
+// StreamInterceptor audits streams without a payload digest, as their
+// requests are a sequence of messages.
+func StreamInterceptor(svc goaudit.Service) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !svc.Match(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		start := time.Now()
+		ctx := gotex.WithVerifiedIdentity(ss.Context())
+		err := handler(srv, gogrpc.WrapServerStream(ss, ctx))
+		svc.Record(ctx, info.FullMethod, nil, start, err)
+		return err
+	}
+}
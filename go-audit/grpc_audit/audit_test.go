@@ -0,0 +1,102 @@
+package grpc_audit
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	goaudit "pkg.tanyudii.me/go-pkg/go-audit"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+)
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	var records []*goaudit.Record
+	svc := goaudit.NewService(
+		goaudit.Methods("/user.Service/Create*"),
+		goaudit.WithSink(goaudit.SinkFunc(func(_ context.Context, r *goaudit.Record) error {
+			records = append(records, r)
+			return nil
+		})),
+	)
+	interceptor := UnaryInterceptor(svc)
+	handler := func(_ context.Context, _ interface{}) (interface{}, error) {
+		return nil, status.Error(codes.InvalidArgument, "invalid")
+	}
+
+	_, err := interceptor(context.Background(), map[string]string{"name": "alice"}, &grpc.UnaryServerInfo{FullMethod: "/user.Service/CreateUser"}, handler)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("handler error should be returned, got %v", err)
+	}
+	_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/user.Service/GetUser"}, handler)
+
+	if len(records) != 1 {
+		t.Fatalf("only the matched method should be audited, got %d records", len(records))
+	}
+	if r := records[0]; r.Method != "/user.Service/CreateUser" || r.ResultCode != codes.InvalidArgument.String() || r.PayloadDigest == "" {
+		t.Errorf("unexpected record %+v", r)
+	}
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	var records []*goaudit.Record
+	svc := goaudit.NewService(goaudit.WithSink(goaudit.SinkFunc(func(_ context.Context, r *goaudit.Record) error {
+		records = append(records, r)
+		return nil
+	})))
+	interceptor := StreamInterceptor(svc)
+	ss := &serverStream{ctx: context.Background()}
+
+	err := interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/user.Service/Import"}, func(_ interface{}, _ grpc.ServerStream) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Method != "/user.Service/Import" || records[0].ResultCode != "OK" || records[0].PayloadDigest != "" {
+		t.Errorf("stream should be audited without a payload digest, got %+v", records)
+	}
+}
+
+func TestUnaryInterceptorActor(t *testing.T) {
+	var records []*goaudit.Record
+	svc := goaudit.NewService(goaudit.WithSink(goaudit.SinkFunc(func(_ context.Context, r *goaudit.Record) error {
+		records = append(records, r)
+		return nil
+	})))
+	interceptor := UnaryInterceptor(svc)
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.Service/Delete"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		gotex.RequestHeaderKeyUserID, "spoofed-user",
+		gotex.RequestHeaderKeyCompanyID, "spoofed-company",
+		gotex.RequestHeaderKeyRequestID, "req-1",
+	))
+
+	_, _ = interceptor(ctx, nil, info, func(_ context.Context, _ interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	})
+	_, _ = interceptor(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		gotex.SetVerifiedIdentity(ctx, &gotex.Gotex{UserID: "user-1", CompanyID: "company-1"})
+		return nil, nil
+	})
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if r := records[0]; r.UserID != "" || r.CompanyID != "" || r.RequestID != "req-1" {
+		t.Errorf("identity headers should not be recorded without auth, got %+v", r)
+	}
+	if r := records[1]; r.UserID != "user-1" || r.CompanyID != "company-1" {
+		t.Errorf("the verified identity should be recorded, got %+v", r)
+	}
+}
@@ -0,0 +1,21 @@
+package go_audit
+
+import (
+	"time"
+)
+
+// Record is one audited call. The gorm tags describe the table of the gorm
+// sink.
+type Record struct {
+	ID            uint64    `json:"id,omitempty" gorm:"primaryKey;autoIncrement"`
+	Timestamp     time.Time `json:"timestamp" gorm:"index;not null"`
+	Method        string    `json:"method" gorm:"size:255;index;not null"`
+	RequestID     string    `json:"request_id" gorm:"size:128;index"`
+	UserID        string    `json:"user_id" gorm:"size:64;index"`
+	UserType      string    `json:"user_type" gorm:"size:64"`
+	ClientID      string    `json:"client_id" gorm:"size:64"`
+	CompanyID     string    `json:"company_id" gorm:"size:64;index"`
+	PayloadDigest string    `json:"payload_digest" gorm:"size:64"`
+	ResultCode    string    `json:"result_code" gorm:"size:32;not null"`
+	DurationMs    int64     `json:"duration_ms"`
+}
@@ -0,0 +1,144 @@
+package go_audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+	"time"
+)
+
+const (
+	writeTimeout = 5 * time.Second
+)
+
+type Service interface {
+	// Match reports whether method is audited.
+	Match(method string) bool
+	// Record writes the record of a finished call to every sink. Sink
+	// errors are logged, they never fail the call. The sinks run on a fresh
+	// context, the one of the call may be done already. The actor is the
+	// identity auth verified, see gotex.WithVerifiedIdentity, and empty for
+	// unauthenticated calls.
+	Record(ctx context.Context, method string, req interface{}, start time.Time, err error)
+}
+
+type service struct {
+	cfg *Config
+}
+
+func NewService(args ...ConfigFunc) Service {
+	return &service{
+		cfg: generate(args...),
+	}
+}
+
+func (s *service) Match(method string) bool {
+	if matchAny(s.cfg.skipMethods, method) {
+		return false
+	}
+	return len(s.cfg.methods) == 0 || matchAny(s.cfg.methods, method)
+}
+
+func (s *service) Record(ctx context.Context, method string, req interface{}, start time.Time, err error) {
+	r := s.newRecord(ctx, method, req, start, err)
+	wctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	for _, sink := range s.cfg.sinks {
+		if werr := sink.Write(wctx, r); werr != nil {
+			gologger.Errorf("go audit: failed to write record of %s %v", method, werr)
+		}
+	}
+}
+
+func (s *service) newRecord(ctx context.Context, method string, req interface{}, start time.Time, err error) *Record {
+	gtx, ok := gotex.FromContext(ctx)
+	if !ok {
+		gtx = gotex.NewGotex(gotex.FromIncoming(ctx))
+	}
+	r := &Record{
+		Timestamp:     start,
+		Method:        method,
+		RequestID:     gtx.RequestID,
+		PayloadDigest: s.digest(req),
+		ResultCode:    status.Code(err).String(),
+		DurationMs:    time.Since(start).Milliseconds(),
+	}
+	// the actor is only taken from auth, never from headers of the caller
+	if actor := gotex.VerifiedIdentity(ctx); actor != nil {
+		r.UserID = actor.UserID
+		r.UserType = actor.UserType
+		r.ClientID = actor.ClientID
+		r.CompanyID = actor.CompanyID
+	}
+	return r
+}
+
+// digest is the sha256 of the request as JSON, without the redacted fields,
+// so records can be matched against payloads without storing them.
+func (s *service) digest(req interface{}) string {
+	if req == nil {
+		return ""
+	}
+	var b []byte
+	var err error
+	if m, ok := req.(proto.Message); ok {
+		b, err = protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	} else {
+		b, err = json.Marshal(req)
+	}
+	if err != nil {
+		gologger.Errorf("go audit: failed to marshal payload %v", err)
+		return ""
+	}
+
+	var v interface{}
+	if err = json.Unmarshal(b, &v); err != nil {
+		gologger.Errorf("go audit: failed to unmarshal payload %v", err)
+		return ""
+	}
+	// encoding/json sorts map keys, so the digest is stable
+	if b, err = json.Marshal(s.redact(v)); err != nil {
+		gologger.Errorf("go audit: failed to marshal payload %v", err)
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *service) redact(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if s.cfg.redactFields[strings.ToLower(k)] {
+				delete(t, k)
+				continue
+			}
+			t[k] = s.redact(child)
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = s.redact(t[i])
+		}
+	}
+	return v
+}
+
+func matchAny(patterns []string, method string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(method, prefix) {
+				return true
+			}
+		} else if p == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package go_audit
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+	"time"
+)
+
+func TestServiceRecord(t *testing.T) {
+	var records []*Record
+	svc := NewService(
+		Methods("/user.Service/Create*", "/user.Service/Delete"),
+		WithSink(SinkFunc(func(_ context.Context, r *Record) error {
+			records = append(records, r)
+			return nil
+		})),
+	)
+
+	if svc.Match("/user.Service/Get") || svc.Match("/grpc.health.v1.Health/Check") {
+		t.Error("only the configured methods should be audited")
+	}
+	if !svc.Match("/user.Service/CreateUser") || !svc.Match("/user.Service/Delete") {
+		t.Error("configured methods should be audited")
+	}
+
+	ctx := gotex.NewContext(context.Background(), &gotex.Gotex{RequestID: "req-1"})
+	ctx = gotex.SetVerifiedIdentity(ctx, &gotex.Gotex{
+		UserID:    "user-1",
+		UserType:  "admin",
+		CompanyID: "company-1",
+	})
+	payload := func(name, password string) *structpb.Struct {
+		s, _ := structpb.NewStruct(map[string]interface{}{
+			"name": name,
+			"credentials": map[string]interface{}{
+				"password": password,
+			},
+		})
+		return s
+	}
+	start := time.Now()
+	svc.Record(ctx, "/user.Service/CreateUser", payload("alice", "secret-1"), start, nil)
+	svc.Record(ctx, "/user.Service/CreateUser", payload("alice", "secret-2"), start, nil)
+	svc.Record(ctx, "/user.Service/CreateUser", payload("bob", "secret-1"), start, status.Error(codes.AlreadyExists, "exists"))
+
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	r := records[0]
+	if r.UserID != "user-1" || r.UserType != "admin" || r.CompanyID != "company-1" || r.RequestID != "req-1" || r.ResultCode != "OK" {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if r.PayloadDigest == "" || r.PayloadDigest != records[1].PayloadDigest {
+		t.Errorf("redacted fields should not change the digest, got %q and %q", r.PayloadDigest, records[1].PayloadDigest)
+	}
+	if r.PayloadDigest == records[2].PayloadDigest {
+		t.Error("other payloads should have another digest")
+	}
+	if records[2].ResultCode != codes.AlreadyExists.String() {
+		t.Errorf("result code should be AlreadyExists, got %s", records[2].ResultCode)
+	}
+}
+
+func TestServiceRecordAfterCancel(t *testing.T) {
+	var werr error
+	svc := NewService(WithSink(SinkFunc(func(ctx context.Context, _ *Record) error {
+		werr = ctx.Err()
+		return nil
+	})))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	svc.Record(ctx, "/user.Service/CreateUser", nil, time.Now(), nil)
+
+	if werr != nil {
+		t.Errorf("sinks should not get the context of the call, got %v", werr)
+	}
+}
@@ -0,0 +1,116 @@
+package go_audit
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/taskq/v3"
+	"gorm.io/gorm"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	goqueue "pkg.tanyudii.me/go-pkg/go-queue"
+)
+
+const (
+	DefaultTableName = "audit_records"
+	DefaultTaskName  = "audit-record"
+)
+
+type Sink interface {
+	Write(ctx context.Context, r *Record) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, r *Record) error
+
+func (f SinkFunc) Write(ctx context.Context, r *Record) error {
+	return f(ctx, r)
+}
+
+type loggerSink struct{}
+
+// NewLoggerSink writes records as structured go-logger entries.
+func NewLoggerSink() Sink {
+	return loggerSink{}
+}
+
+func (loggerSink) Write(_ context.Context, r *Record) error {
+	gologger.WithFields(logrus.Fields{
+		"audit":          true,
+		"timestamp":      r.Timestamp,
+		"method":         r.Method,
+		"request_id":     r.RequestID,
+		"user_id":        r.UserID,
+		"user_type":      r.UserType,
+		"client_id":      r.ClientID,
+		"company_id":     r.CompanyID,
+		"payload_digest": r.PayloadDigest,
+		"result_code":    r.ResultCode,
+		"duration_ms":    r.DurationMs,
+	}).Info("audit")
+	return nil
+}
+
+type gormSink struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewGormSink inserts records into table, DefaultTableName when empty, e.g.
+// with a db from connection/mysql. The table can be created with
+// db.Table(table).AutoMigrate(&Record{}).
+func NewGormSink(db *gorm.DB, table string) Sink {
+	if table == "" {
+		table = DefaultTableName
+	}
+	return &gormSink{db: db, table: table}
+}
+
+func (s *gormSink) Write(ctx context.Context, r *Record) error {
+	// the record is copied so the auto increment id stays out of the record
+	// shared with the other sinks
+	row := *r
+	return s.db.WithContext(ctx).Table(s.table).Create(&row).Error
+}
+
+type queueSink struct {
+	queue    goqueue.Service
+	taskName string
+}
+
+// NewQueueSink publishes records as go-queue messages of taskName,
+// DefaultTaskName when empty, to persist them asynchronously with the
+// worker of NewQueueWorker.
+func NewQueueSink(q goqueue.Service, taskName string) Sink {
+	if taskName == "" {
+		taskName = DefaultTaskName
+	}
+	return &queueSink{queue: q, taskName: taskName}
+}
+
+func (s *queueSink) Write(ctx context.Context, r *Record) error {
+	return s.queue.AddMessage(ctx, s.taskName, r)
+}
+
+type queueWorker struct {
+	taskName string
+	sink     Sink
+}
+
+// NewQueueWorker consumes the messages of NewQueueSink into sink, e.g. a
+// gorm sink.
+func NewQueueWorker(taskName string, sink Sink) goqueue.Worker {
+	if taskName == "" {
+		taskName = DefaultTaskName
+	}
+	return &queueWorker{taskName: taskName, sink: sink}
+}
+
+func (w *queueWorker) GetTasks() []*taskq.TaskOptions {
+	return []*taskq.TaskOptions{
+		{
+			Name: w.taskName,
+			Handler: func(ctx context.Context, r *Record) error {
+				return w.sink.Write(ctx, r)
+			},
+		},
+	}
+}
@@ -0,0 +1,84 @@
+package go_audit
+
+import (
+	"context"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	goqueue "pkg.tanyudii.me/go-pkg/go-queue"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGormSink(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/audit",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sql string
+	var vars []interface{}
+	err = db.Callback().Create().After("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+		vars = tx.Statement.Vars
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Record{Timestamp: time.Now(), Method: "/user.Service/CreateUser", UserID: "user-1", ResultCode: "OK"}
+	if err = NewGormSink(db, "").Write(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(sql, "INSERT INTO `"+DefaultTableName+"`") {
+		t.Errorf("record should be inserted into %s, got %q", DefaultTableName, sql)
+	}
+	if len(vars) == 0 || vars[1] != r.Method {
+		t.Errorf("record fields should be inserted, got %v", vars)
+	}
+	if r.ID != 0 {
+		t.Error("the shared record should not be changed")
+	}
+}
+
+type fakeQueue struct {
+	goqueue.Service
+	taskName string
+	args     []interface{}
+}
+
+func (q *fakeQueue) AddMessage(_ context.Context, name string, args ...interface{}) error {
+	q.taskName = name
+	q.args = args
+	return nil
+}
+
+func TestQueueSink(t *testing.T) {
+	q := &fakeQueue{}
+	r := &Record{Method: "/user.Service/CreateUser"}
+	if err := NewQueueSink(q, "").Write(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	if q.taskName != DefaultTaskName || len(q.args) != 1 || q.args[0] != r {
+		t.Errorf("record should be published as %s, got %s %v", DefaultTaskName, q.taskName, q.args)
+	}
+
+	var written *Record
+	tasks := NewQueueWorker("", SinkFunc(func(_ context.Context, r *Record) error {
+		written = r
+		return nil
+	})).GetTasks()
+	if len(tasks) != 1 || tasks[0].Name != DefaultTaskName {
+		t.Fatalf("worker should consume %s, got %+v", DefaultTaskName, tasks)
+	}
+	handler, ok := tasks[0].Handler.(func(context.Context, *Record) error)
+	if !ok {
+		t.Fatalf("unexpected handler %T", tasks[0].Handler)
+	}
+	if err := handler(context.Background(), r); err != nil || written != r {
+		t.Errorf("worker should write the record to the sink, got %v %v", written, err)
+	}
+}
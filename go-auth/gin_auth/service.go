@@ -54,7 +54,14 @@ func (s *service) authenticate(c *gin.Context) (context.Context, error) {
 		return nil, err
 	}
 
-	return s.authService.Authenticate(newCtx, fullMethod)
+	if newCtx, err = s.authService.Authenticate(newCtx, fullMethod); err != nil {
+		return nil, err
+	}
+	// records the gotex of the authenticated ctx as verified identity
+	if gtx, ok := gotex.FromContext(newCtx); ok {
+		newCtx = gotex.SetVerifiedIdentity(newCtx, gtx)
+	}
+	return newCtx, nil
 }
 
 func (s *service) authenticateBearer(c *gin.Context) (context.Context, error) {
@@ -1,7 +1,8 @@
 package grpc_auth
 
 type Config struct {
-	InternalCallPassword string
+	InternalCallPassword  string
+	TrustedPeerIdentities []string
 }
 
 type ConfigFunc func(c *Config)
@@ -12,6 +13,14 @@ func InternalCallPassword(pwd string) ConfigFunc {
 	}
 }
 
+// TrustedPeerIdentities treats calls from a verified mTLS client certificate
+// with one of the given CN or SAN values as internal calls.
+func TrustedPeerIdentities(identities ...string) ConfigFunc {
+	return func(c *Config) {
+		c.TrustedPeerIdentities = append(c.TrustedPeerIdentities, identities...)
+	}
+}
+
 func generate(args ...ConfigFunc) *Config {
 	c := &Config{}
 	for i := range args {
@@ -36,7 +36,11 @@ func (s *service) authenticate(ctx context.Context, info *grpc.UnaryServerInfo)
 	}
 
 	if newCtx, ok := s.authorizedInternalCall(ctx); ok {
-		return newCtx, nil
+		return verified(newCtx), nil
+	}
+
+	if newCtx, ok := s.authorizedPeerIdentity(ctx); ok {
+		return verified(newCtx), nil
 	}
 
 	newCtx, err := s.authenticateBearer(ctx)
@@ -44,7 +48,18 @@ func (s *service) authenticate(ctx context.Context, info *grpc.UnaryServerInfo)
 		return nil, err
 	}
 
-	return s.authService.Authenticate(newCtx, info.FullMethod)
+	if newCtx, err = s.authService.Authenticate(newCtx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return verified(newCtx), nil
+}
+
+// verified records the gotex of an authenticated ctx as verified identity.
+func verified(ctx context.Context) context.Context {
+	if gtx, ok := gotex.FromContext(ctx); ok {
+		return gotex.SetVerifiedIdentity(ctx, gtx)
+	}
+	return ctx
 }
 
 func (s *service) authenticateBearer(ctx context.Context) (context.Context, error) {
@@ -87,3 +102,15 @@ func (s *service) authorizedInternalCall(ctx context.Context) (context.Context,
 		// internal call password is equal with internal call password from request
 		s.cfg.InternalCallPassword != "" && gtx.InternalCallPassword == s.cfg.InternalCallPassword
 }
+
+func (s *service) authorizedPeerIdentity(ctx context.Context) (context.Context, bool) {
+	if len(s.cfg.TrustedPeerIdentities) == 0 {
+		return ctx, false
+	}
+	gtx, ok := gotex.FromContext(ctx)
+	if !ok {
+		return ctx, false
+	}
+	// peer identity is only filled from a verified client certificate
+	return ctx, gtx.HasPeerIdentity(s.cfg.TrustedPeerIdentities)
+}
@@ -12,3 +12,13 @@ type RouteConfig interface {
 	GetScopes() []string
 	GetUserTypes() []string
 }
+
+// RouteRequirement is what the route maps require of callers of a method.
+type RouteRequirement struct {
+	Public      bool     `json:"public"`
+	UserTypes   []string `json:"user_types,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	// Dynamic is set when a RouteService may require more at runtime.
+	Dynamic bool `json:"dynamic,omitempty"`
+}
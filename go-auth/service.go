@@ -9,6 +9,7 @@ import (
 type Service interface {
 	IsPublicRoute(fullMethod string) (bool, error)
 	Authenticate(ctx context.Context, fullMethod string) (context.Context, error)
+	GetRouteRequirement(fullMethod string) RouteRequirement
 }
 
 type service struct {
@@ -67,6 +68,16 @@ func (s *service) Authenticate(ctx context.Context, fullMethod string) (context.
 	return ctx, nil
 }
 
+func (s *service) GetRouteRequirement(fullMethod string) RouteRequirement {
+	return RouteRequirement{
+		Public:      s.cfg.mapPublicRoutes[fullMethod],
+		UserTypes:   s.cfg.mapUserTypeRoutes[fullMethod],
+		Permissions: s.cfg.mapPermissionRoutes[fullMethod],
+		Scopes:      s.cfg.mapScopeRoutes[fullMethod],
+		Dynamic:     s.cfg.routeService != nil,
+	}
+}
+
 func (s *service) authorizedUserType(session *gotex.Gotex, userTypes []string) (bool, error) {
 	ok, err := session.HasUserTypeByMapCode(s.cfg.mapUserTypeTrusted)
 	if err != nil {
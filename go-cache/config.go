@@ -0,0 +1,83 @@
+package go_cache
+
+import (
+	"context"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"time"
+)
+
+// MethodPolicy enables caching of a read-only method.
+//
+// By default responses are shared by every caller of the same company and
+// locale, see DefaultKeyFields. Methods answering per user must set
+// KeyFields including gotex.RequestHeaderKeyUserID, or one user is served
+// the response cached for another. The user, user type, company and client
+// fields are taken from the identity auth verified, never from the headers.
+type MethodPolicy struct {
+	// TTL is how long a response is served from cache.
+	TTL time.Duration
+	// Tags index the cached responses of the method, so write paths can
+	// invalidate them with Service.Invalidate.
+	Tags []string
+	// TagFunc adds tags derived from the request, e.g. "order:<id>".
+	TagFunc func(ctx context.Context, req interface{}) []string
+	// KeyFields replace the key fields of the service for this method.
+	KeyFields []string
+}
+
+type MapMethodPolicies map[string]MethodPolicy
+
+var (
+	// DefaultKeyFields scope cached responses to the company and locale of
+	// the caller, not to the user.
+	DefaultKeyFields = []string{
+		gotex.RequestHeaderKeyCompanyID,
+		gotex.RequestHeaderKeyAcceptLanguage,
+	}
+)
+
+type Config struct {
+	store     Store
+	methods   MapMethodPolicies
+	keyFields []string
+}
+
+type ConfigFunc func(c *Config)
+
+func WithStore(s Store) ConfigFunc {
+	return func(c *Config) {
+		c.store = s
+	}
+}
+
+// Methods enables caching per full method, e.g. "/order.Service/Get".
+// Methods without a policy or with a zero TTL are never cached.
+func Methods(m MapMethodPolicies) ConfigFunc {
+	return func(c *Config) {
+		for method, p := range m {
+			c.methods[method] = p
+		}
+	}
+}
+
+// KeyFields are the gotex request headers, besides the method and request,
+// that make up the cache key. It replaces DefaultKeyFields.
+func KeyFields(fields ...string) ConfigFunc {
+	return func(c *Config) {
+		c.keyFields = fields
+	}
+}
+
+func generate(args ...ConfigFunc) *Config {
+	c := &Config{
+		methods:   make(MapMethodPolicies),
+		keyFields: DefaultKeyFields,
+	}
+	for i := range args {
+		args[i](c)
+	}
+	if c.store == nil {
+		c.store = NewLocalStore()
+	}
+	return c
+}
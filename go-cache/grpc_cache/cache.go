@@ -0,0 +1,74 @@
+package grpc_cache
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	gocache "pkg.tanyudii.me/go-pkg/go-cache"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+)
+
+// UnaryInterceptor serves responses of methods with a cache policy from the
+// cache, storing successful responses on a miss. Only read-only methods
+// should have a policy. It should run after authentication, so rejected
+// callers never read cached responses.
+func UnaryInterceptor(svc gocache.Service) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		policy, ok := svc.Policy(info.FullMethod)
+		msg, isMsg := req.(proto.Message)
+		if !ok || !isMsg {
+			return handler(ctx, req)
+		}
+
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return handler(ctx, req)
+		}
+		key := svc.Key(ctx, info.FullMethod, b)
+		if cached, hit := svc.Get(ctx, info.FullMethod, key); hit {
+			resp, uerr := unmarshal(cached)
+			if uerr == nil {
+				return resp, nil
+			}
+			gologger.Errorf("go cache: failed to unmarshal response %v", uerr)
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		if out, ok := resp.(proto.Message); ok {
+			if value, merr := marshal(out); merr == nil {
+				svc.Set(ctx, info.FullMethod, key, value, tags(ctx, policy, req))
+			} else {
+				gologger.Errorf("go cache: failed to marshal response %v", merr)
+			}
+		}
+		return resp, nil
+	}
+}
+
+func tags(ctx context.Context, p gocache.MethodPolicy, req interface{}) []string {
+	t := append([]string{}, p.Tags...)
+	if p.TagFunc != nil {
+		t = append(t, p.TagFunc(ctx, req)...)
+	}
+	return t
+}
+
+func marshal(msg proto.Message) ([]byte, error) {
+	a, err := anypb.New(msg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(a)
+}
+
+func unmarshal(b []byte) (interface{}, error) {
+	a := &anypb.Any{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return nil, err
+	}
+	return a.UnmarshalNew()
+}
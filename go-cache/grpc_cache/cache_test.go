@@ -0,0 +1,90 @@
+package grpc_cache
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	gocache "pkg.tanyudii.me/go-pkg/go-cache"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+	"time"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	svc := gocache.NewService(gocache.Methods(gocache.MapMethodPolicies{
+		"/order.Service/Get": {TTL: time.Minute, Tags: []string{"orders"}},
+	}))
+	interceptor := UnaryInterceptor(svc)
+	info := &grpc.UnaryServerInfo{FullMethod: "/order.Service/Get"}
+	companyA := gotex.SetVerifiedIdentity(context.Background(), &gotex.Gotex{CompanyID: "a"})
+	companyB := gotex.SetVerifiedIdentity(context.Background(), &gotex.Gotex{CompanyID: "b"})
+
+	var calls int
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return wrapperspb.String("order-1"), nil
+	}
+	call := func(ctx context.Context, req proto.Message) {
+		resp, err := interceptor(ctx, req, info, handler)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !proto.Equal(resp.(proto.Message), wrapperspb.String("order-1")) {
+			t.Errorf("unexpected response %v", resp)
+		}
+	}
+
+	call(companyA, wrapperspb.String("1"))
+	call(companyA, wrapperspb.String("1"))
+	if calls != 1 {
+		t.Errorf("second call should hit the cache, handler ran %d times", calls)
+	}
+	call(companyB, wrapperspb.String("1"))
+	call(companyA, wrapperspb.String("2"))
+	if calls != 3 {
+		t.Errorf("key should include company and request, handler ran %d times", calls)
+	}
+
+	if err := svc.Invalidate(context.Background(), "orders"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	call(companyA, wrapperspb.String("1"))
+	if calls != 4 {
+		t.Errorf("invalidated response should not be served, handler ran %d times", calls)
+	}
+}
+
+func TestUnaryInterceptorSkip(t *testing.T) {
+	svc := gocache.NewService(gocache.Methods(gocache.MapMethodPolicies{
+		"/order.Service/Get": {TTL: time.Minute},
+	}))
+	interceptor := UnaryInterceptor(svc)
+
+	var calls int
+	failing := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, errors.New("failed")
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/order.Service/Get"}
+	for i := 0; i < 2; i++ {
+		_, _ = interceptor(context.Background(), wrapperspb.String("1"), info, failing)
+	}
+	if calls != 2 {
+		t.Errorf("errors should not be cached, handler ran %d times", calls)
+	}
+
+	calls = 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return wrapperspb.String("order-1"), nil
+	}
+	info = &grpc.UnaryServerInfo{FullMethod: "/order.Service/Create"}
+	for i := 0; i < 2; i++ {
+		_, _ = interceptor(context.Background(), wrapperspb.String("1"), info, handler)
+	}
+	if calls != 2 {
+		t.Errorf("methods without a policy should not be cached, handler ran %d times", calls)
+	}
+}
@@ -0,0 +1,136 @@
+package go_cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/prometheus/client_golang/prometheus"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+)
+
+const (
+	keyPrefix = "cache"
+
+	ResultHit   = "hit"
+	ResultMiss  = "miss"
+	ResultError = "error"
+)
+
+var (
+	CacheRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_cache_requests_total",
+		Help: "GRPC response cache lookups by result.",
+	}, []string{"grpcMethod", "result"})
+)
+
+// Collectors returns the cache metrics, to be registered with the go-grpc
+// service, e.g. svc.RegisterPrometheusCollector(gocache.Collectors()...).
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{CacheRequestsCounter}
+}
+
+type Service interface {
+	// Policy returns the policy of method and whether it is cached.
+	Policy(method string) (MethodPolicy, bool)
+	// Key derives the cache key of a call from its method, marshalled
+	// request and the key fields of the incoming metadata, those of the
+	// method policy when it has any.
+	Key(ctx context.Context, method string, request []byte) string
+	// Get returns a cached response. Store errors count as a miss.
+	Get(ctx context.Context, method, key string) ([]byte, bool)
+	Set(ctx context.Context, method, key string, value []byte, tags []string)
+	// Invalidate drops every response cached under one of tags.
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+type service struct {
+	cfg *Config
+}
+
+func NewService(args ...ConfigFunc) Service {
+	return &service{
+		cfg: generate(args...),
+	}
+}
+
+func (s *service) Policy(method string) (MethodPolicy, bool) {
+	p, ok := s.cfg.methods[method]
+	return p, ok && p.TTL > 0
+}
+
+func (s *service) Key(ctx context.Context, method string, request []byte) string {
+	fields := s.cfg.keyFields
+	if p, ok := s.cfg.methods[method]; ok && len(p.KeyFields) > 0 {
+		fields = p.KeyFields
+	}
+	actor := gotex.VerifiedIdentity(ctx)
+	md := gotex.FromIncoming(ctx)
+	h := sha256.New()
+	for _, f := range fields {
+		v, ok := identityField(actor, f)
+		if !ok {
+			v = md.Get(strings.ToLower(f))
+		}
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	h.Write(request)
+	return keyPrefix + ":" + method + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// identityField returns the value of an identity field from the identity
+// auth verified, empty for unauthenticated calls, so a caller can not claim
+// the cached responses of another tenant with a header.
+func identityField(actor *gotex.Gotex, field string) (string, bool) {
+	if actor == nil {
+		actor = &gotex.Gotex{}
+	}
+	switch field {
+	case gotex.RequestHeaderKeyUserID:
+		return actor.UserID, true
+	case gotex.RequestHeaderKeyUserType:
+		return actor.UserType, true
+	case gotex.RequestHeaderKeyCompanyID:
+		return actor.CompanyID, true
+	case gotex.RequestHeaderKeyClientID:
+		return actor.ClientID, true
+	}
+	return "", false
+}
+
+func (s *service) Get(ctx context.Context, method, key string) ([]byte, bool) {
+	b, ok, err := s.cfg.store.Get(ctx, key)
+	switch {
+	case err != nil:
+		gologger.Errorf("go cache: failed to get %v", err)
+		CacheRequestsCounter.WithLabelValues(method, ResultError).Inc()
+	case ok:
+		CacheRequestsCounter.WithLabelValues(method, ResultHit).Inc()
+	default:
+		CacheRequestsCounter.WithLabelValues(method, ResultMiss).Inc()
+	}
+	return b, ok && err == nil
+}
+
+func (s *service) Set(ctx context.Context, method, key string, value []byte, tags []string) {
+	p, ok := s.Policy(method)
+	if !ok {
+		return
+	}
+	if err := s.cfg.store.Set(ctx, key, value, p.TTL, tags); err != nil {
+		gologger.Errorf("go cache: failed to set %v", err)
+	}
+}
+
+func (s *service) Invalidate(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	if err := s.cfg.store.InvalidateTags(ctx, tags...); err != nil {
+		gologger.Errorf("go cache: failed to invalidate %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package go_cache
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceKeyFields(t *testing.T) {
+	svc := NewService(Methods(MapMethodPolicies{
+		"/order.Service/List":   {TTL: time.Minute},
+		"/order.Service/ListMy": {TTL: time.Minute, KeyFields: append([]string{gotex.RequestHeaderKeyUserID}, DefaultKeyFields...)},
+	}))
+	ctxOf := func(userID string) context.Context {
+		return verifiedContext(&gotex.Gotex{UserID: userID, CompanyID: "company-1"}, metadata.MD{})
+	}
+
+	if svc.Key(ctxOf("user-1"), "/order.Service/List", nil) != svc.Key(ctxOf("user-2"), "/order.Service/List", nil) {
+		t.Error("responses should be shared within the company by default")
+	}
+	if svc.Key(ctxOf("user-1"), "/order.Service/ListMy", nil) == svc.Key(ctxOf("user-2"), "/order.Service/ListMy", nil) {
+		t.Error("policy key fields should scope responses to the user")
+	}
+}
+
+func TestServiceKeyVerifiedIdentity(t *testing.T) {
+	svc := NewService(Methods(MapMethodPolicies{"/order.Service/List": {TTL: time.Minute}}))
+	tenant := verifiedContext(&gotex.Gotex{CompanyID: "company-1"}, metadata.Pairs(
+		strings.ToLower(gotex.RequestHeaderKeyAcceptLanguage), "en",
+	))
+	spoofed := verifiedContext(&gotex.Gotex{CompanyID: "company-2"}, metadata.Pairs(
+		strings.ToLower(gotex.RequestHeaderKeyCompanyID), "company-1",
+		strings.ToLower(gotex.RequestHeaderKeyAcceptLanguage), "en",
+	))
+	unauthenticated := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		strings.ToLower(gotex.RequestHeaderKeyCompanyID), "company-1",
+		strings.ToLower(gotex.RequestHeaderKeyAcceptLanguage), "en",
+	))
+
+	key := svc.Key(tenant, "/order.Service/List", nil)
+	if svc.Key(spoofed, "/order.Service/List", nil) == key || svc.Key(unauthenticated, "/order.Service/List", nil) == key {
+		t.Error("a companyid header should not reach the entries of another tenant")
+	}
+	other := verifiedContext(&gotex.Gotex{CompanyID: "company-1"}, metadata.Pairs(
+		strings.ToLower(gotex.RequestHeaderKeyAcceptLanguage), "id",
+	))
+	if svc.Key(other, "/order.Service/List", nil) == key {
+		t.Error("the locale should still be read from the headers")
+	}
+}
+
+func verifiedContext(gtx *gotex.Gotex, md metadata.MD) context.Context {
+	return gotex.SetVerifiedIdentity(metadata.NewIncomingContext(context.Background(), md), gtx)
+}
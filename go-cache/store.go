@@ -0,0 +1,183 @@
+package go_cache
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"sync"
+	"time"
+)
+
+type Store interface {
+	// Get returns the value of key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key and indexes key under every tag.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	// InvalidateTags removes every key indexed under one of tags.
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+type redisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore keeps entries in redis, shared by every instance of a
+// service, e.g. with a client from connection/redis. Tags are redis sets of
+// keys living as long as their longest entry, which needs redis 7.
+func NewRedisStore(client *redis.Client) Store {
+	return &redisStore{client: client}
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, value, ttl)
+		for _, tag := range tags {
+			pipe.SAdd(ctx, tagKey(tag), key)
+			pipe.ExpireGT(ctx, tagKey(tag), ttl)
+			pipe.ExpireNX(ctx, tagKey(tag), ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *redisStore) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := s.client.SMembers(ctx, tagKey(tag)).Result()
+		if err != nil {
+			return err
+		}
+		if err = s.client.Del(ctx, append(keys, tagKey(tag))...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tagKey(tag string) string {
+	return keyPrefix + ":tag:" + tag
+}
+
+const (
+	// DefaultLocalStoreSize is the number of entries NewLocalStore holds.
+	DefaultLocalStoreSize = 10000
+)
+
+// localStore keeps entries in process, for single instances and tests.
+// Expired entries are swept periodically and, once full, new entries are
+// not stored until the sweep makes room.
+type localStore struct {
+	mu         sync.Mutex
+	entries    map[string]*localEntry
+	tags       map[string]map[string]struct{}
+	maxEntries int
+	sweptAt    time.Time
+	sweepEach  time.Duration
+}
+
+type localEntry struct {
+	value     []byte
+	tags      []string
+	expiresAt time.Time
+}
+
+func NewLocalStore() Store {
+	return NewLocalStoreSize(DefaultLocalStoreSize)
+}
+
+// NewLocalStoreSize is NewLocalStore holding at most maxEntries entries.
+func NewLocalStoreSize(maxEntries int) Store {
+	if maxEntries <= 0 {
+		maxEntries = DefaultLocalStoreSize
+	}
+	return &localStore{
+		entries:    make(map[string]*localEntry),
+		tags:       make(map[string]map[string]struct{}),
+		maxEntries: maxEntries,
+		sweptAt:    time.Now(),
+		sweepEach:  time.Minute,
+	}
+}
+
+func (s *localStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(e.expiresAt) {
+		s.delete(key)
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (s *localStore) Set(_ context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	s.delete(key)
+	if len(s.entries) >= s.maxEntries {
+		return nil
+	}
+	s.entries[key] = &localEntry{value: value, tags: tags, expiresAt: now.Add(ttl)}
+	for _, tag := range tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[string]struct{})
+		}
+		s.tags[tag][key] = struct{}{}
+	}
+	return nil
+}
+
+func (s *localStore) InvalidateTags(_ context.Context, tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			s.delete(key)
+		}
+		delete(s.tags, tag)
+	}
+	return nil
+}
+
+// delete drops key along with its place in the tag sets.
+func (s *localStore) delete(key string) {
+	e, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	delete(s.entries, key)
+	for _, tag := range e.tags {
+		delete(s.tags[tag], key)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+func (s *localStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < s.sweepEach {
+		return
+	}
+	s.sweptAt = now
+	for key, e := range s.entries {
+		if now.After(e.expiresAt) {
+			s.delete(key)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package go_cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLocalStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalStoreSize(2).(*localStore)
+
+	_ = s.Set(ctx, "a", []byte("a"), time.Millisecond, []string{"order"})
+	_ = s.Set(ctx, "b", []byte("b"), time.Minute, []string{"order"})
+	_ = s.Set(ctx, "c", []byte("c"), time.Minute, nil)
+	if _, ok, _ := s.Get(ctx, "c"); ok {
+		t.Error("a full store should not take new entries")
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	s.sweptAt = time.Time{}
+	_ = s.Set(ctx, "c", []byte("c"), time.Minute, nil)
+	if _, ok, _ := s.Get(ctx, "c"); !ok {
+		t.Error("the sweep should make room for new entries")
+	}
+	if _, ok := s.entries["a"]; ok {
+		t.Error("expired entries should be swept without being read")
+	}
+	if _, ok := s.tags["order"]["a"]; ok {
+		t.Error("swept entries should leave their tags")
+	}
+
+	_ = s.InvalidateTags(ctx, "order")
+	if _, ok, _ := s.Get(ctx, "b"); ok || len(s.tags) != 0 {
+		t.Errorf("invalidated entries should be gone, got %d tags", len(s.tags))
+	}
+}
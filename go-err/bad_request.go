@@ -127,6 +127,6 @@ func IsBadRequestError(err error) bool {
 	if IsBadRequestErrorGRPC(err) {
 		return true
 	}
-	var expectedErr BadRequestError
+	var expectedErr *BadRequestError
 	return errors.As(err, &expectedErr)
 }
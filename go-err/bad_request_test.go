@@ -0,0 +1,21 @@
+package go_err
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsBadRequestError(t *testing.T) {
+	if !IsBadRequestError(NewBadRequestErrorWithFields("invalid", ErrorField{"name": "required"})) {
+		t.Error("bad request errors should match")
+	}
+	// constructors return *BadRequestError, which the value type target of
+	// errors.As never matched
+	err := fmt.Errorf("validate: %w", &BadRequestError{BaseError: &BaseError{Message: "invalid"}})
+	if !IsBadRequestError(err) {
+		t.Error("wrapped *BadRequestError should match")
+	}
+	if IsBadRequestError(NewNotFoundError("missing")) {
+		t.Error("other errors should not match")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"strconv"
+	"time"
 )
 
 type CustomError interface {
@@ -16,6 +18,7 @@ type CustomError interface {
 	GetHTTPCode() int
 	GetFields() ErrorField
 	SetFields(v ErrorField)
+	GetMetadata() map[string]string
 }
 
 type BaseError struct {
@@ -25,6 +28,12 @@ type BaseError struct {
 	GRPCCode codes.Code
 	HTTPCode int
 	Fields   ErrorField
+
+	// RetryAfter hints the client when to retry, sent as errdetails.RetryInfo.
+	RetryAfter time.Duration
+	// Metadata is sent along the code and name in errdetails.ErrorInfo,
+	// e.g. the request id of a recovered panic.
+	Metadata map[string]string
 }
 
 func (i *BaseError) Error() string {
@@ -39,6 +48,9 @@ func (i *BaseError) GRPCStatus() *status.Status {
 	if fields := i.getBadRequestFields(); fields != nil {
 		stats, _ = stats.WithDetails(fields)
 	}
+	if i.RetryAfter > 0 {
+		stats, _ = stats.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(i.RetryAfter)})
+	}
 	return stats
 }
 
@@ -66,8 +78,15 @@ func (i *BaseError) SetFields(v ErrorField) {
 	i.Fields = v
 }
 
+func (i *BaseError) GetMetadata() map[string]string {
+	return i.Metadata
+}
+
 func (i *BaseError) getErrorInfoCustom() *errdetails.ErrorInfo {
 	metaData := make(map[string]string)
+	for k, v := range i.Metadata {
+		metaData[k] = v
+	}
 	if i.Code != 0 {
 		metaData[metaKeyErrorCode] = strconv.Itoa(i.Code)
 	}
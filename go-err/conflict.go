@@ -0,0 +1,72 @@
+package go_err
+
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"net/http"
+)
+
+const (
+	conflictGRPCCode = codes.Aborted
+	conflictHTTPCode = http.StatusConflict
+)
+
+type ConflictError struct {
+	*BaseError
+}
+
+func NewConflictError(msg string) error {
+	return &ConflictError{
+		BaseError: &BaseError{
+			Message:  msg,
+			GRPCCode: conflictGRPCCode,
+			HTTPCode: conflictHTTPCode,
+		},
+	}
+}
+
+func NewConflictErrorWithCode(msg string, code int) error {
+	return &ConflictError{
+		BaseError: &BaseError{
+			Code:     code,
+			Message:  msg,
+			GRPCCode: conflictGRPCCode,
+			HTTPCode: conflictHTTPCode,
+		},
+	}
+}
+
+func NewConflictErrorWithName(msg string, name string) error {
+	return &ConflictError{
+		BaseError: &BaseError{
+			Name:     name,
+			Message:  msg,
+			GRPCCode: conflictGRPCCode,
+			HTTPCode: conflictHTTPCode,
+		},
+	}
+}
+
+func NewConflictErrorWithCodeAndName(msg string, code int, name string) error {
+	return &ConflictError{
+		BaseError: &BaseError{
+			Code:     code,
+			Name:     name,
+			Message:  msg,
+			GRPCCode: conflictGRPCCode,
+			HTTPCode: conflictHTTPCode,
+		},
+	}
+}
+
+func IsConflictErrorGRPC(err error) bool {
+	return GetErrorGRPCCodeFromErrorGRPC(err) == conflictGRPCCode
+}
+
+func IsConflictError(err error) bool {
+	if IsConflictErrorGRPC(err) {
+		return true
+	}
+	var expectedErr *ConflictError
+	return errors.As(err, &expectedErr)
+}
@@ -0,0 +1,72 @@
+package go_err
+
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"net/http"
+)
+
+const (
+	deadlineExceededGRPCCode = codes.DeadlineExceeded
+	deadlineExceededHTTPCode = http.StatusGatewayTimeout
+)
+
+type DeadlineExceededError struct {
+	*BaseError
+}
+
+func NewDeadlineExceededError(msg string) error {
+	return &DeadlineExceededError{
+		BaseError: &BaseError{
+			Message:  msg,
+			GRPCCode: deadlineExceededGRPCCode,
+			HTTPCode: deadlineExceededHTTPCode,
+		},
+	}
+}
+
+func NewDeadlineExceededErrorWithCode(msg string, code int) error {
+	return &DeadlineExceededError{
+		BaseError: &BaseError{
+			Code:     code,
+			Message:  msg,
+			GRPCCode: deadlineExceededGRPCCode,
+			HTTPCode: deadlineExceededHTTPCode,
+		},
+	}
+}
+
+func NewDeadlineExceededErrorWithName(msg string, name string) error {
+	return &DeadlineExceededError{
+		BaseError: &BaseError{
+			Name:     name,
+			Message:  msg,
+			GRPCCode: deadlineExceededGRPCCode,
+			HTTPCode: deadlineExceededHTTPCode,
+		},
+	}
+}
+
+func NewDeadlineExceededErrorWithCodeAndName(msg string, code int, name string) error {
+	return &DeadlineExceededError{
+		BaseError: &BaseError{
+			Code:     code,
+			Name:     name,
+			Message:  msg,
+			GRPCCode: deadlineExceededGRPCCode,
+			HTTPCode: deadlineExceededHTTPCode,
+		},
+	}
+}
+
+func IsDeadlineExceededErrorGRPC(err error) bool {
+	return GetErrorGRPCCodeFromErrorGRPC(err) == deadlineExceededGRPCCode
+}
+
+func IsDeadlineExceededError(err error) bool {
+	if IsDeadlineExceededErrorGRPC(err) {
+		return true
+	}
+	var expectedErr *DeadlineExceededError
+	return errors.As(err, &expectedErr)
+}
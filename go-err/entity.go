@@ -29,10 +29,11 @@ type ResponseError struct {
 }
 
 type ErrorMeta struct {
-	Code     int        `json:"code,omitempty"`
-	Name     string     `json:"name,omitempty"`
-	GrpcCode codes.Code `json:"grpcCode,omitempty"`
-	HttpCode int        `json:"httpCode,omitempty"`
+	Code     int               `json:"code,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	GrpcCode codes.Code        `json:"grpcCode,omitempty"`
+	HttpCode int               `json:"httpCode,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 func NewResponseError(c CustomError) *ResponseError {
@@ -44,6 +45,7 @@ func NewResponseError(c CustomError) *ResponseError {
 			Name:     c.GetName(),
 			GrpcCode: c.GetGRPCCode(),
 			HttpCode: c.GetHTTPCode(),
+			Metadata: c.GetMetadata(),
 		},
 		Fields: c.GetFields(),
 	}
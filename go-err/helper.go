@@ -6,6 +6,7 @@ import (
 	"google.golang.org/grpc/status"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 const (
@@ -37,6 +38,15 @@ func FromStatus(s *status.Status) CustomError {
 			if name, ok := errInfo.Metadata[metaKeyErrorName]; ok {
 				base.Name = name
 			}
+			for k, v := range errInfo.Metadata {
+				if k == metaKeyErrorCode || k == metaKeyErrorName {
+					continue
+				}
+				if base.Metadata == nil {
+					base.Metadata = make(map[string]string)
+				}
+				base.Metadata[k] = v
+			}
 			continue
 		}
 
@@ -49,6 +59,13 @@ func FromStatus(s *status.Status) CustomError {
 			}
 			continue
 		}
+
+		//get retry delay
+		retryInfo, valid := detail.(*errdetails.RetryInfo)
+		if valid && retryInfo.RetryDelay != nil {
+			base.RetryAfter = retryInfo.RetryDelay.AsDuration()
+			continue
+		}
 	}
 
 	switch s.Code() {
@@ -58,10 +75,16 @@ func FromStatus(s *status.Status) CustomError {
 		return &NotFoundError{BaseError: base}
 	case tooManyRequestGRPCCode:
 		return &TooManyRequestError{BaseError: base}
+	case deadlineExceededGRPCCode:
+		return &DeadlineExceededError{BaseError: base}
+	case conflictGRPCCode:
+		return &ConflictError{BaseError: base}
 	case unauthenticatedGRPCCode:
 		return &UnauthenticatedError{BaseError: base}
 	case unauthorizedGRPCCode:
 		return &UnauthorizedError{BaseError: base}
+	case serviceUnavailableGRPCCode:
+		return &ServiceUnavailableError{BaseError: base}
 	default:
 		return &InternalServerError{BaseError: base}
 	}
@@ -81,6 +104,7 @@ func FromResponseError(r *ResponseError) error {
 		GRPCCode: r.Meta.GrpcCode,
 		HTTPCode: r.Meta.HttpCode,
 		Fields:   r.Fields,
+		Metadata: r.Meta.Metadata,
 	}
 
 	switch r.Meta.GrpcCode {
@@ -90,10 +114,16 @@ func FromResponseError(r *ResponseError) error {
 		return &NotFoundError{BaseError: base}
 	case tooManyRequestGRPCCode:
 		return &TooManyRequestError{BaseError: base}
+	case deadlineExceededGRPCCode:
+		return &DeadlineExceededError{BaseError: base}
+	case conflictGRPCCode:
+		return &ConflictError{BaseError: base}
 	case unauthenticatedGRPCCode:
 		return &UnauthenticatedError{BaseError: base}
 	case unauthorizedGRPCCode:
 		return &UnauthorizedError{BaseError: base}
+	case serviceUnavailableGRPCCode:
+		return &ServiceUnavailableError{BaseError: base}
 	default:
 		return &InternalServerError{BaseError: base}
 	}
@@ -151,6 +181,17 @@ func GetErrorName(err error) string {
 	return ""
 }
 
+func GetRetryAfter(err error) time.Duration {
+	details := GetErrorDetailsFromErrorGRPC(err)
+	for _, detail := range details {
+		retryInfo, valid := detail.(*errdetails.RetryInfo)
+		if valid && retryInfo.RetryDelay != nil {
+			return retryInfo.RetryDelay.AsDuration()
+		}
+	}
+	return 0
+}
+
 func IsErrorCode(err error, code int) bool {
 	return GetErrorCode(err) == code
 }
@@ -59,6 +59,18 @@ func NewInternalServerErrorWithCodeAndName(msg string, code int, name string) er
 	}
 }
 
+func NewInternalServerErrorWithNameAndMetadata(msg string, name string, metadata map[string]string) error {
+	return &InternalServerError{
+		&BaseError{
+			Name:     name,
+			Message:  msg,
+			GRPCCode: internalServerGRPCCode,
+			HTTPCode: internalServerHTTPCode,
+			Metadata: metadata,
+		},
+	}
+}
+
 func IsInternalServerErrorGRPC(err error) bool {
 	return GetErrorGRPCCodeFromErrorGRPC(err) == internalServerGRPCCode
 }
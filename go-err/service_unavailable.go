@@ -0,0 +1,62 @@
+package go_err
+
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"net/http"
+	"time"
+)
+
+const (
+	serviceUnavailableGRPCCode = codes.Unavailable
+	serviceUnavailableHTTPCode = http.StatusServiceUnavailable
+)
+
+type ServiceUnavailableError struct {
+	*BaseError
+}
+
+func NewServiceUnavailableError(msg string) error {
+	return &ServiceUnavailableError{
+		BaseError: &BaseError{
+			Message:  msg,
+			GRPCCode: serviceUnavailableGRPCCode,
+			HTTPCode: serviceUnavailableHTTPCode,
+		},
+	}
+}
+
+func NewServiceUnavailableErrorWithName(msg string, name string) error {
+	return &ServiceUnavailableError{
+		BaseError: &BaseError{
+			Name:     name,
+			Message:  msg,
+			GRPCCode: serviceUnavailableGRPCCode,
+			HTTPCode: serviceUnavailableHTTPCode,
+		},
+	}
+}
+
+func NewServiceUnavailableErrorWithNameAndRetryAfter(msg string, name string, retryAfter time.Duration) error {
+	return &ServiceUnavailableError{
+		BaseError: &BaseError{
+			Name:       name,
+			Message:    msg,
+			GRPCCode:   serviceUnavailableGRPCCode,
+			HTTPCode:   serviceUnavailableHTTPCode,
+			RetryAfter: retryAfter,
+		},
+	}
+}
+
+func IsServiceUnavailableErrorGRPC(err error) bool {
+	return GetErrorGRPCCodeFromErrorGRPC(err) == serviceUnavailableGRPCCode
+}
+
+func IsServiceUnavailableError(err error) bool {
+	if IsServiceUnavailableErrorGRPC(err) {
+		return true
+	}
+	var expectedErr *ServiceUnavailableError
+	return errors.As(err, &expectedErr)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"google.golang.org/grpc/codes"
 	"net/http"
+	"time"
 )
 
 const (
@@ -59,6 +60,29 @@ func NewTooManyRequestErrorWithCodeAndName(msg string, code int, name string) er
 	}
 }
 
+func NewTooManyRequestErrorWithRetryAfter(msg string, retryAfter time.Duration) error {
+	return &TooManyRequestError{
+		BaseError: &BaseError{
+			Message:    msg,
+			GRPCCode:   tooManyRequestGRPCCode,
+			HTTPCode:   tooManyRequestHTTPCode,
+			RetryAfter: retryAfter,
+		},
+	}
+}
+
+func NewTooManyRequestErrorWithNameAndRetryAfter(msg string, name string, retryAfter time.Duration) error {
+	return &TooManyRequestError{
+		BaseError: &BaseError{
+			Name:       name,
+			Message:    msg,
+			GRPCCode:   tooManyRequestGRPCCode,
+			HTTPCode:   tooManyRequestHTTPCode,
+			RetryAfter: retryAfter,
+		},
+	}
+}
+
 func IsTooManyRequestErrorGRPC(err error) bool {
 	return GetErrorGRPCCodeFromErrorGRPC(err) == tooManyRequestGRPCCode
 }
@@ -16,38 +16,46 @@ type UnauthorizedError struct {
 }
 
 func NewUnauthorizedError(msg string) error {
-	return &BaseError{
-		Message:  msg,
-		GRPCCode: unauthorizedGRPCCode,
-		HTTPCode: unauthorizedHTTPCode,
+	return &UnauthorizedError{
+		&BaseError{
+			Message:  msg,
+			GRPCCode: unauthorizedGRPCCode,
+			HTTPCode: unauthorizedHTTPCode,
+		},
 	}
 }
 
 func NewUnauthorizedErrorWithCode(msg string, code int) error {
-	return &BaseError{
-		Code:     code,
-		Message:  msg,
-		GRPCCode: unauthorizedGRPCCode,
-		HTTPCode: unauthorizedHTTPCode,
+	return &UnauthorizedError{
+		&BaseError{
+			Code:     code,
+			Message:  msg,
+			GRPCCode: unauthorizedGRPCCode,
+			HTTPCode: unauthorizedHTTPCode,
+		},
 	}
 }
 
 func NewUnauthorizedErrorWithName(msg string, name string) error {
-	return &BaseError{
-		Name:     name,
-		Message:  msg,
-		GRPCCode: unauthorizedGRPCCode,
-		HTTPCode: unauthorizedHTTPCode,
+	return &UnauthorizedError{
+		&BaseError{
+			Name:     name,
+			Message:  msg,
+			GRPCCode: unauthorizedGRPCCode,
+			HTTPCode: unauthorizedHTTPCode,
+		},
 	}
 }
 
 func NewUnauthorizedErrorWithCodeAndName(msg string, code int, name string) error {
-	return &BaseError{
-		Code:     code,
-		Name:     name,
-		Message:  msg,
-		GRPCCode: unauthorizedGRPCCode,
-		HTTPCode: unauthorizedHTTPCode,
+	return &UnauthorizedError{
+		&BaseError{
+			Code:     code,
+			Name:     name,
+			Message:  msg,
+			GRPCCode: unauthorizedGRPCCode,
+			HTTPCode: unauthorizedHTTPCode,
+		},
 	}
 }
 
@@ -56,9 +64,9 @@ func IsUnauthorizedErrorGRPC(err error) bool {
 }
 
 func IsUnauthorizedError(err error) bool {
-	if IsUnauthenticatedErrorGRPC(err) {
+	if IsUnauthorizedErrorGRPC(err) {
 		return true
 	}
-	var expectedErr UnauthorizedError
+	var expectedErr *UnauthorizedError
 	return errors.As(err, &expectedErr)
 }
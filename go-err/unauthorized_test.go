@@ -0,0 +1,24 @@
+package go_err
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsUnauthorizedError(t *testing.T) {
+	if !IsUnauthorizedError(NewUnauthorizedError("forbidden")) {
+		t.Error("unauthorized errors should match")
+	}
+	if !IsUnauthorizedError(fmt.Errorf("check: %w", NewUnauthorizedError("forbidden"))) {
+		t.Error("wrapped unauthorized errors should match")
+	}
+	err := fmt.Errorf("check: %w", &UnauthorizedError{BaseError: &BaseError{Message: "forbidden"}})
+	if !IsUnauthorizedError(err) {
+		t.Error("wrapped *UnauthorizedError without a gRPC code should match")
+	}
+	// before, IsUnauthorizedError matched Unauthenticated instead of its own
+	// PermissionDenied code
+	if IsUnauthorizedError(NewUnauthenticatedError("no token")) {
+		t.Error("unauthenticated errors should not match")
+	}
+}
@@ -0,0 +1,224 @@
+package go_grpc
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"net/http"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	gotrace "pkg.tanyudii.me/go-pkg/go-trace"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAccessLogSampleRate    = 1
+	DefaultAccessLogSlowThreshold = time.Second
+)
+
+// AccessLogConfig controls the one line per request access log.
+type AccessLogConfig struct {
+	// SampleRate is the fraction, from 0 to 1, of successful requests that
+	// are logged. Failed and slow requests are always logged.
+	SampleRate float64
+	// SlowThreshold marks requests taking at least this long as slow. Zero
+	// disables it.
+	SlowThreshold time.Duration
+	// SkipMethods are full gRPC methods or HTTP paths that are never logged.
+	SkipMethods []string
+}
+
+func DefaultAccessLogConfig() *AccessLogConfig {
+	return &AccessLogConfig{
+		SampleRate:    DefaultAccessLogSampleRate,
+		SlowThreshold: DefaultAccessLogSlowThreshold,
+		SkipMethods: []string{
+			"/grpc.health.v1.Health/Check",
+			"/grpc.health.v1.Health/Watch",
+			HealthPath,
+			HealthLivenessPath,
+			HealthReadinessPath,
+		},
+	}
+}
+
+type accessLogger struct {
+	cfg    *AccessLogConfig
+	skip   map[string]bool
+	sample func() float64
+}
+
+func newAccessLogger(cfg *AccessLogConfig) *accessLogger {
+	if cfg == nil {
+		return nil
+	}
+	skip := make(map[string]bool, len(cfg.SkipMethods))
+	for _, m := range cfg.SkipMethods {
+		skip[m] = true
+	}
+	return &accessLogger{
+		cfg:    cfg,
+		skip:   skip,
+		sample: rand.Float64,
+	}
+}
+
+func (l *accessLogger) unaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if l.skip[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		start := time.Now()
+		ctx = gotex.WithVerifiedIdentity(ctx)
+		resp, err = handler(ctx, req)
+		l.logRPC(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func (l *accessLogger) streamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		if l.skip[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		start := time.Now()
+		ctx := gotex.WithVerifiedIdentity(ss.Context())
+		err = handler(srv, WrapServerStream(ss, ctx))
+		l.logRPC(ctx, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// identityFields are the ids of the identity verified by auth during the
+// call, empty when it was not authenticated. Identity headers claimed by the
+// client are never logged as fact.
+func identityFields(ctx context.Context) logrus.Fields {
+	gtx := gotex.VerifiedIdentity(ctx)
+	if gtx == nil {
+		gtx = &gotex.Gotex{}
+	}
+	return logrus.Fields{
+		"user_id":    gtx.UserID,
+		"company_id": gtx.CompanyID,
+		"client_id":  gtx.ClientID,
+	}
+}
+
+func (l *accessLogger) logRPC(ctx context.Context, fullMethod string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+	slow := l.isSlow(duration)
+	if err == nil && !slow && !l.sampled() {
+		return
+	}
+
+	md := gotex.FromIncoming(ctx)
+	userAgent := md.Get(HeaderGRPCUserAgent)
+	if userAgent == "" {
+		userAgent = md.Get(HeaderUserAgent)
+	}
+	var peerAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		peerAddr = p.Addr.String()
+	}
+
+	entry := gologger.WithFields(logrus.Fields{
+		"protocol":    "grpc",
+		"method":      fullMethod,
+		"duration_ms": duration.Milliseconds(),
+		"grpc_code":   code.String(),
+		"request_id":  md.Get(strings.ToLower(gotex.RequestHeaderKeyRequestID)),
+		"peer":        peerAddr,
+		"user_agent":  userAgent,
+		"slow":        slow,
+		"trace_id":    gotrace.TraceID(ctx),
+	}).WithFields(identityFields(ctx))
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+
+	entry.Log(rpcLogLevel(code, slow), "grpc access")
+}
+
+func (l *accessLogger) httpMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.skip[r.URL.Path] {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// the request id is generated here instead of in the gRPC
+		// interceptor so both access log entries share it
+		requestID := r.Header.Get(gotex.RequestHeaderKeyRequestID)
+		if requestID == "" {
+			requestID = newRequestID()
+			r.Header.Set(gotex.RequestHeaderKeyRequestID, requestID)
+		}
+
+		start := time.Now()
+		r, route := withHTTPRoute(r)
+		// mounted handlers verify the identity in this process, gateway
+		// calls log it on their grpc entry sharing the request id
+		r = r.WithContext(gotex.WithVerifiedIdentity(r.Context()))
+		rw := newResponseWriter(w)
+		h.ServeHTTP(rw, r)
+
+		duration := time.Since(start)
+		slow := l.isSlow(duration)
+		if rw.status < http.StatusBadRequest && !slow && !l.sampled() {
+			return
+		}
+
+		gologger.WithFields(logrus.Fields{
+			"protocol":     "http",
+			"method":       r.Method,
+			"path":         r.URL.Path,
+			"route":        route.pattern,
+			"duration_ms":  duration.Milliseconds(),
+			"http_status":  rw.status,
+			"request_id":   requestID,
+			"peer":         r.RemoteAddr,
+			"user_agent":   r.UserAgent(),
+			"slow":         slow,
+			"response_len": rw.size,
+			"trace_id":     gotrace.TraceID(r.Context()),
+		}).WithFields(identityFields(r.Context())).Log(httpLogLevel(rw.status, slow), "http access")
+	})
+}
+
+func (l *accessLogger) isSlow(d time.Duration) bool {
+	return l.cfg.SlowThreshold > 0 && d >= l.cfg.SlowThreshold
+}
+
+func (l *accessLogger) sampled() bool {
+	return l.cfg.SampleRate >= 1 || l.sample() < l.cfg.SampleRate
+}
+
+func rpcLogLevel(code codes.Code, slow bool) logrus.Level {
+	switch code {
+	case codes.OK:
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.DeadlineExceeded:
+		return logrus.ErrorLevel
+	default:
+		return logrus.WarnLevel
+	}
+	if slow {
+		return logrus.WarnLevel
+	}
+	return logrus.InfoLevel
+}
+
+func httpLogLevel(status int, slow bool) logrus.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return logrus.ErrorLevel
+	case status >= http.StatusBadRequest, slow:
+		return logrus.WarnLevel
+	}
+	return logrus.InfoLevel
+}
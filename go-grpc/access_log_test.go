@@ -0,0 +1,103 @@
+package go_grpc
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+	"time"
+)
+
+func TestAccessLogUnaryServerInterceptor(t *testing.T) {
+	hook := test.NewLocal(gologger.GetLogger())
+	l := newAccessLogger(&AccessLogConfig{SampleRate: 0, SlowThreshold: 20 * time.Millisecond})
+	interceptor := l.unaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/order.Service/Get"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"requestid", "req-1",
+		"userid", "spoofed",
+	))
+
+	_, _ = interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if len(hook.AllEntries()) != 0 {
+		t.Fatalf("successful request should be sampled out, got %d entries", len(hook.AllEntries()))
+	}
+
+	_, _ = interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "boom")
+	})
+	if entry := hook.LastEntry(); entry == nil || entry.Data["user_id"] != "" {
+		t.Fatalf("client claimed user should not be logged, got %v", entry)
+	}
+
+	_, _ = interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		// as done by the auth interceptor
+		gotex.SetVerifiedIdentity(ctx, &gotex.Gotex{UserID: "user-1"})
+		return nil, status.Error(codes.Internal, "boom")
+	})
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("failed request should always be logged")
+	}
+	if entry.Level != logrus.ErrorLevel {
+		t.Errorf("level should be error, got %v", entry.Level)
+	}
+	for key, want := range map[string]interface{}{
+		"method":     "/order.Service/Get",
+		"grpc_code":  "Internal",
+		"request_id": "req-1",
+		"user_id":    "user-1",
+	} {
+		if got := entry.Data[key]; got != want {
+			t.Errorf("field %s should be %v, got %v", key, want, got)
+		}
+	}
+
+	hook.Reset()
+	_, _ = interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		time.Sleep(25 * time.Millisecond)
+		return nil, nil
+	})
+	if entry = hook.LastEntry(); entry == nil || entry.Data["slow"] != true || entry.Level != logrus.WarnLevel {
+		t.Errorf("slow request should be logged as warning, got %v", entry)
+	}
+}
+
+func TestAccessLogHTTPMiddleware(t *testing.T) {
+	hook := test.NewLocal(gologger.GetLogger())
+	l := newAccessLogger(DefaultAccessLogConfig())
+
+	var forwardedID string
+	h := l.httpMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedID = r.Header.Get("RequestID")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/orders/1", nil))
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("request should be logged")
+	}
+	if forwardedID == "" || entry.Data["request_id"] != forwardedID {
+		t.Errorf("generated request id should be forwarded and logged, got %q and %v", forwardedID, entry.Data["request_id"])
+	}
+	if entry.Data["http_status"] != http.StatusNotFound || entry.Level != logrus.WarnLevel {
+		t.Errorf("not found should be logged as warning, got %v at %v", entry.Data["http_status"], entry.Level)
+	}
+
+	hook.Reset()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, HealthLivenessPath, nil))
+	if len(hook.AllEntries()) != 0 {
+		t.Errorf("health check should not be logged")
+	}
+}
@@ -0,0 +1,273 @@
+package go_grpc
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/keepalive"
+	"net/http"
+	"net/http/pprof"
+	goauth "pkg.tanyudii.me/go-pkg/go-auth"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gomaintenance "pkg.tanyudii.me/go-pkg/go-maintenance"
+	"sort"
+	"strings"
+)
+
+const (
+	AdminConfigPath      = "/admin/config"
+	AdminMethodsPath     = "/admin/methods"
+	AdminLogLevelPath    = "/admin/log-level"
+	AdminMaintenancePath = "/admin/maintenance"
+
+	adminRedacted = "[REDACTED]"
+)
+
+// AdminConfig enables the admin and debug endpoints on the metrics port.
+type AdminConfig struct {
+	// Token is required as bearer token by every admin and debug endpoint.
+	// It is mandatory, without it the endpoints are not served.
+	Token string
+	// Auth describes the auth requirements in the method list.
+	Auth goauth.Service
+	// Maintenance is switched by the maintenance endpoint.
+	Maintenance gomaintenance.Service
+}
+
+type adminMethod struct {
+	Method       string                   `json:"method"`
+	ClientStream bool                     `json:"client_stream"`
+	ServerStream bool                     `json:"server_stream"`
+	Auth         *goauth.RouteRequirement `json:"auth,omitempty"`
+}
+
+type adminLogLevel struct {
+	Level string `json:"level"`
+}
+
+func (s *service) registerAdminHandlers(mux *http.ServeMux) {
+	if s.cfg.admin == nil {
+		return
+	}
+	if s.cfg.admin.Token == "" {
+		gologger.Error("go grpc admin: endpoints are not served without a token")
+		return
+	}
+	mux.Handle("/debug/pprof/", s.adminOnly(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", s.adminOnly(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", s.adminOnly(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", s.adminOnly(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", s.adminOnly(http.HandlerFunc(pprof.Trace)))
+	mux.Handle("/debug/vars", s.adminOnly(expvar.Handler()))
+	mux.Handle(AdminConfigPath, s.adminOnly(http.HandlerFunc(s.adminConfigHandler)))
+	mux.Handle(AdminMethodsPath, s.adminOnly(http.HandlerFunc(s.adminMethodsHandler)))
+	mux.Handle(AdminLogLevelPath, s.adminOnly(http.HandlerFunc(s.adminLogLevelHandler)))
+	if s.cfg.admin.Maintenance != nil {
+		mux.Handle(AdminMaintenancePath, s.adminOnly(http.HandlerFunc(s.adminMaintenanceHandler)))
+	}
+}
+
+// isAdminPath reports whether path belongs to the metrics port, for the
+// single port listener to route it there.
+func (s *service) isAdminPath(path string) bool {
+	return s.cfg.admin != nil && s.cfg.admin.Token != "" && (strings.HasPrefix(path, "/debug/") || strings.HasPrefix(path, "/admin/"))
+}
+
+func (s *service) adminOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := s.cfg.admin.Token
+		given := strings.TrimPrefix(r.Header.Get(HeaderAuthorization), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (s *service) adminConfigHandler(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, http.StatusOK, s.cfg.view())
+}
+
+func (s *service) adminMethodsHandler(w http.ResponseWriter, _ *http.Request) {
+	methods := make([]adminMethod, 0)
+	if s.server != nil {
+		for name, info := range s.server.GetServiceInfo() {
+			for _, m := range info.Methods {
+				method := adminMethod{
+					Method:       "/" + name + "/" + m.Name,
+					ClientStream: m.IsClientStream,
+					ServerStream: m.IsServerStream,
+				}
+				if s.cfg.admin.Auth != nil {
+					req := s.cfg.admin.Auth.GetRouteRequirement(method.Method)
+					method.Auth = &req
+				}
+				methods = append(methods, method)
+			}
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Method < methods[j].Method
+	})
+	writeAdminJSON(w, http.StatusOK, methods)
+}
+
+func (s *service) adminLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var body adminLogLevel
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		level, err := logrus.ParseLevel(body.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		gologger.Warnf("go grpc admin: log level changed from %s to %s", gologger.GetLevel(), level)
+		gologger.SetLevel(level)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, adminLogLevel{Level: gologger.GetLevel().String()})
+}
+
+func (s *service) adminMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	svc := s.cfg.admin.Maintenance
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var body gomaintenance.State
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := svc.Set(r.Context(), &body); err != nil {
+			gologger.Errorf("go grpc admin: failed to set maintenance %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	state, err := svc.State(r.Context())
+	if err != nil {
+		gologger.Errorf("go grpc admin: failed to get maintenance %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, state)
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set(HeaderContentType, "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		gologger.Errorf("go grpc admin: failed to write response %v", err)
+	}
+}
+
+// configView is the effective Config as dumped by the admin endpoint, with
+// secrets redacted.
+type configView struct {
+	GRPCPort                 string                       `json:"grpc_port"`
+	RESTPort                 string                       `json:"rest_port"`
+	PrometheusPort           string                       `json:"prometheus_port"`
+	SinglePort               string                       `json:"single_port,omitempty"`
+	SinglePortMetrics        bool                         `json:"single_port_metrics"`
+	EnablePrometheus         bool                         `json:"enable_prometheus"`
+	EnableCORS               bool                         `json:"enable_cors"`
+	CORSPolicy               *CORSPolicy                  `json:"cors_policy,omitempty"`
+	OnlyJSON                 bool                         `json:"only_json"`
+	RegisterReflection       bool                         `json:"register_reflection"`
+	TLS                      bool                         `json:"tls"`
+	DiscardUnknown           bool                         `json:"discard_unknown"`
+	RESTServeMuxOpts         int                          `json:"rest_serve_mux_opts"`
+	EnableRPCMetrics         bool                         `json:"enable_rpc_metrics"`
+	EnableInFlightMetrics    bool                         `json:"enable_in_flight_metrics"`
+	EnableMessageSizeMetrics bool                         `json:"enable_message_size_metrics"`
+	EnableHTTPMetrics        bool                         `json:"enable_http_metrics"`
+	EnableTracing            bool                         `json:"enable_tracing"`
+	HealthCheckInterval      string                       `json:"health_check_interval"`
+	HealthCheckTimeout       string                       `json:"health_check_timeout"`
+	ShutdownDrainPeriod      string                       `json:"shutdown_drain_period"`
+	ShutdownTimeout          string                       `json:"shutdown_timeout"`
+	GRPCTLS                  *TLSConfig                   `json:"grpc_tls,omitempty"`
+	RESTTLS                  *TLSConfig                   `json:"rest_tls,omitempty"`
+	PrometheusTLS            *TLSConfig                   `json:"prometheus_tls,omitempty"`
+	TLSReloadInterval        string                       `json:"tls_reload_interval"`
+	AccessLog                *AccessLogConfig             `json:"access_log,omitempty"`
+	DefaultTimeout           string                       `json:"default_timeout"`
+	MethodTimeouts           map[string]string            `json:"method_timeouts,omitempty"`
+	MaxRecvMsgSize           int                          `json:"max_recv_msg_size"`
+	MaxSendMsgSize           int                          `json:"max_send_msg_size"`
+	MaxConcurrentStreams     uint32                       `json:"max_concurrent_streams,omitempty"`
+	Keepalive                *keepalive.ServerParameters  `json:"keepalive,omitempty"`
+	KeepaliveEnforcement     *keepalive.EnforcementPolicy `json:"keepalive_enforcement,omitempty"`
+	Gzip                     *int                         `json:"gzip,omitempty"`
+	ServerOpts               int                          `json:"server_opts"`
+	PanicHooks               int                          `json:"panic_hooks"`
+	AdminToken               string                       `json:"admin_token,omitempty"`
+}
+
+func (c *Config) view() configView {
+	v := configView{
+		GRPCPort:                 c.gRPCPort,
+		RESTPort:                 c.restPort,
+		PrometheusPort:           c.prometheusPort,
+		SinglePort:               c.singlePort,
+		SinglePortMetrics:        c.singlePortMetrics,
+		EnablePrometheus:         c.enablePrometheus,
+		EnableCORS:               c.enableCORS,
+		CORSPolicy:               c.corsPolicy,
+		OnlyJSON:                 c.onlyJSON,
+		RegisterReflection:       c.registerReflection,
+		TLS:                      c.tls,
+		DiscardUnknown:           c.discardUnknown,
+		RESTServeMuxOpts:         len(c.restServeMuxOpts),
+		EnableRPCMetrics:         c.enableRPCMetrics,
+		EnableInFlightMetrics:    c.enableInFlightMetrics,
+		EnableMessageSizeMetrics: c.enableMessageSizeMetrics,
+		EnableHTTPMetrics:        c.enableHTTPMetrics,
+		EnableTracing:            c.enableTracing,
+		HealthCheckInterval:      c.healthCheckInterval.String(),
+		HealthCheckTimeout:       c.healthCheckTimeout.String(),
+		ShutdownDrainPeriod:      c.shutdownDrainPeriod.String(),
+		ShutdownTimeout:          c.shutdownTimeout.String(),
+		GRPCTLS:                  c.gRPCTLS,
+		RESTTLS:                  c.restTLS,
+		PrometheusTLS:            c.prometheusTLS,
+		TLSReloadInterval:        c.tlsReloadInterval.String(),
+		AccessLog:                c.accessLog,
+		DefaultTimeout:           c.defaultTimeout.String(),
+		MaxRecvMsgSize:           c.maxRecvMsgSize,
+		MaxSendMsgSize:           c.maxSendMsgSize,
+		MaxConcurrentStreams:     c.maxConcurrentStreams,
+		Keepalive:                c.keepalive,
+		KeepaliveEnforcement:     c.keepaliveEnforcement,
+		Gzip:                     c.gzipLevel,
+		ServerOpts:               len(c.serverOpts),
+		PanicHooks:               len(c.panicHooks),
+	}
+	if len(c.methodTimeouts) > 0 {
+		v.MethodTimeouts = make(map[string]string, len(c.methodTimeouts))
+		for method, d := range c.methodTimeouts {
+			v.MethodTimeouts[method] = d.String()
+		}
+	}
+	if c.admin != nil && c.admin.Token != "" {
+		v.AdminToken = adminRedacted
+	}
+	return v
+}
@@ -0,0 +1,92 @@
+package go_grpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"net/http"
+	"net/http/httptest"
+	goauth "pkg.tanyudii.me/go-pkg/go-auth"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gomaintenance "pkg.tanyudii.me/go-pkg/go-maintenance"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandlers(t *testing.T) {
+	maintenance := gomaintenance.NewService()
+	s := NewService(Admin(&AdminConfig{
+		Token:       "secret",
+		Auth:        goauth.NewService(),
+		Maintenance: maintenance,
+	})).(*service)
+	s.server = grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(s.server, health.NewServer())
+	h := s.prometheusHTTPHandler()
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set(HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	if rec := serve(http.MethodGet, AdminConfigPath, "wrong", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token should be rejected, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/debug/vars", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("debug endpoints should need the token, got %d", rec.Code)
+	}
+
+	rec := serve(http.MethodGet, AdminConfigPath, "secret", "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "secret") || !strings.Contains(rec.Body.String(), adminRedacted) {
+		t.Errorf("config should be dumped with the token redacted, got %d %s", rec.Code, rec.Body)
+	}
+
+	var methods []adminMethod
+	rec = serve(http.MethodGet, AdminMethodsPath, "secret", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &methods); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(methods) != 2 || methods[0].Method != "/grpc.health.v1.Health/Check" || methods[0].Auth == nil || !methods[0].Auth.Public {
+		t.Errorf("unexpected methods %+v", methods)
+	}
+
+	level := gologger.GetLevel()
+	defer gologger.SetLevel(level)
+	rec = serve(http.MethodPut, AdminLogLevelPath, "secret", `{"level":"debug"}`)
+	if rec.Code != http.StatusOK || gologger.GetLevel() != logrus.DebugLevel {
+		t.Errorf("log level should be changed, got %d %s", rec.Code, gologger.GetLevel())
+	}
+
+	rec = serve(http.MethodPut, AdminMaintenancePath, "secret", `{"enabled":true,"methods":["/order.Service/*"]}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"enabled": true`) {
+		t.Errorf("maintenance should be enabled, got %d %s", rec.Code, rec.Body)
+	}
+	if err := maintenance.Check(context.Background(), "/order.Service/Create"); !goerr.IsServiceUnavailableError(err) {
+		t.Errorf("method should be under maintenance, got %v", err)
+	}
+}
+
+func TestAdminWithoutToken(t *testing.T) {
+	s := NewService(Admin(&AdminConfig{})).(*service)
+	h := s.prometheusHTTPHandler()
+
+	for _, path := range []string{"/debug/pprof/", AdminConfigPath, AdminLogLevelPath} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s should not be served without a token, got %d", path, rec.Code)
+		}
+	}
+	if s.isAdminPath(AdminConfigPath) {
+		t.Error("the single port should not route admin paths without a token")
+	}
+}
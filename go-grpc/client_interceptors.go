@@ -0,0 +1,176 @@
+package go_grpc
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+	"time"
+)
+
+// GotexUnaryClientInterceptor propagates the Gotex of the calling context to
+// the outgoing metadata, replacing manual gotex.ParseToGrpcCtx calls.
+func GotexUnaryClientInterceptor(internalCallPassword ...string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withOutgoingGotex(ctx, internalCallPassword...), method, req, reply, cc, opts...)
+	}
+}
+
+func GotexStreamClientInterceptor(internalCallPassword ...string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withOutgoingGotex(ctx, internalCallPassword...), desc, cc, method, opts...)
+	}
+}
+
+// DeadlineUnaryClientInterceptor passes the remaining deadline budget of the
+// calling context on, keeping reserve for the caller to handle the response.
+// Calls whose budget is already spent fail fast without reaching the network.
+func DeadlineUnaryClientInterceptor(reserve time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel, err := withDeadlineBudget(ctx, reserve)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// DeadlineStreamClientInterceptor is DeadlineUnaryClientInterceptor for
+// streams. The budget is released once the stream ends.
+func DeadlineStreamClientInterceptor(reserve time.Duration) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel, err := withDeadlineBudget(ctx, reserve)
+		if err != nil {
+			return nil, err
+		}
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &deadlineClientStream{ClientStream: cs, cancel: cancel}, nil
+	}
+}
+
+// gatewayMarkerUnaryClientInterceptor marks gateway calls, so the server can
+// tell them from direct callers, e.g. to trust X-Forwarded-For.
+func gatewayMarkerUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(gotex.WithGatewayMarker(ctx), method, req, reply, cc, opts...)
+}
+
+func gatewayMarkerStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(gotex.WithGatewayMarker(ctx), desc, cc, method, opts...)
+}
+
+// ErrorUnaryClientInterceptor converts returned statuses into typed goerr
+// errors, so goerr.Is* helpers behave the same across service boundaries.
+func ErrorUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return convertClientError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+func ErrorStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, convertClientError(err)
+		}
+		return &errorClientStream{ClientStream: cs}, nil
+	}
+}
+
+// ClientDefaultInterceptors installs the tracing, gotex, deadline and error
+// client interceptors on a connection.
+func ClientDefaultInterceptors(internalCallPassword ...string) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		ClientUnaryInterceptor(
+			TracingUnaryClientInterceptor(),
+			GotexUnaryClientInterceptor(internalCallPassword...),
+			DeadlineUnaryClientInterceptor(0),
+			ErrorUnaryClientInterceptor(),
+		)(c)
+		ClientStreamInterceptor(
+			TracingStreamClientInterceptor(),
+			GotexStreamClientInterceptor(internalCallPassword...),
+			DeadlineStreamClientInterceptor(0),
+			ErrorStreamClientInterceptor(),
+		)(c)
+	}
+}
+
+type deadlineClientStream struct {
+	grpc.ClientStream
+	cancel context.CancelFunc
+}
+
+func (s *deadlineClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.cancel()
+	}
+	return err
+}
+
+type errorClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorClientStream) SendMsg(m interface{}) error {
+	return convertClientError(s.ClientStream.SendMsg(m))
+}
+
+func (s *errorClientStream) RecvMsg(m interface{}) error {
+	return convertClientError(s.ClientStream.RecvMsg(m))
+}
+
+func (s *errorClientStream) CloseSend() error {
+	return convertClientError(s.ClientStream.CloseSend())
+}
+
+func convertClientError(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+	if s, ok := status.FromError(err); ok {
+		return goerr.FromStatus(s)
+	}
+	return err
+}
+
+func withOutgoingGotex(ctx context.Context, internalCallPassword ...string) context.Context {
+	if _, ok := gotex.FromContext(ctx); !ok {
+		return ctx
+	}
+	explicit := gotex.FromOutgoing(ctx)
+	md := gotex.FromOutgoing(gotex.ParseToGrpcCtx(ctx, internalCallPassword...))
+	// peer identity belongs to the incoming hop only
+	md.Delete(strings.ToLower(gotex.RequestHeaderKeyPeerCommonName))
+	md.Delete(strings.ToLower(gotex.RequestHeaderKeyPeerSANs))
+	for k, v := range explicit {
+		md[k] = v
+	}
+	return md.ToOutgoing(ctx)
+}
+
+func withDeadlineBudget(ctx context.Context, reserve time.Duration) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	remaining := time.Until(deadline) - reserve
+	if remaining <= 0 {
+		return nil, nil, status.Error(codes.DeadlineExceeded, "deadline budget exhausted")
+	}
+	if reserve <= 0 {
+		return ctx, func() {}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, remaining)
+	return ctx, cancel, nil
+}
@@ -0,0 +1,104 @@
+package go_grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+	"time"
+)
+
+func TestGotexUnaryClientInterceptor(t *testing.T) {
+	ctx := gotex.NewContext(context.Background(), &gotex.Gotex{
+		UserID:         "user-1",
+		RequestID:      "request-1",
+		AcceptLanguage: "id_ID",
+		PeerCommonName: "billing",
+	})
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-custom", "custom")
+
+	var md metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := GotexUnaryClientInterceptor()(ctx, "/test.Service/Get", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"userid":          "user-1",
+		"requestid":       "request-1",
+		"accept-language": "id_ID",
+		"x-custom":        "custom",
+		"peercommonname":  "",
+	}
+	for key, val := range expected {
+		var got string
+		if v := md.Get(key); len(v) > 0 {
+			got = v[0]
+		}
+		if got != val {
+			t.Errorf("metadata %s should be '%s', got '%s'", key, val, got)
+		}
+	}
+}
+
+func TestErrorUnaryClientInterceptor(t *testing.T) {
+	invoker := func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		return goerr.NewNotFoundErrorWithName("user not found", "USER_NOT_FOUND").(goerr.CustomError).GRPCStatus().Err()
+	}
+	err := ErrorUnaryClientInterceptor()(context.Background(), "/test.Service/Get", nil, nil, nil, invoker)
+
+	custom, ok := err.(*goerr.NotFoundError)
+	if !ok {
+		t.Fatalf("error should be *NotFoundError, got %T", err)
+	}
+	if custom.GetName() != "USER_NOT_FOUND" {
+		t.Errorf("error name should be 'USER_NOT_FOUND', got '%s'", custom.GetName())
+	}
+	if !goerr.IsNotFoundError(err) {
+		t.Errorf("IsNotFoundError should be true")
+	}
+}
+
+func TestDeadlineUnaryClientInterceptor(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	called := false
+	invoker := func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		called = true
+		return nil
+	}
+	if err := DeadlineUnaryClientInterceptor(time.Second)(ctx, "/test.Service/Get", nil, nil, nil, invoker); err == nil {
+		t.Errorf("error should not be nil when budget is exhausted")
+	}
+	if called {
+		t.Errorf("invoker should not be called when budget is exhausted")
+	}
+}
+
+func TestDeadlineStreamClientInterceptor(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	parent, _ := ctx.Deadline()
+
+	var streamCtx context.Context
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		streamCtx = ctx
+		return nil, nil
+	}
+	if _, err := DeadlineStreamClientInterceptor(300*time.Millisecond)(ctx, &grpc.StreamDesc{}, nil, "/test.Service/Watch", streamer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deadline, ok := streamCtx.Deadline(); !ok || parent.Sub(deadline) < 250*time.Millisecond {
+		t.Errorf("stream deadline should keep the reserve, got %v before the parent", parent.Sub(deadline))
+	}
+
+	if _, err := DeadlineStreamClientInterceptor(2*time.Second)(ctx, &grpc.StreamDesc{}, nil, "/test.Service/Watch", streamer); err == nil {
+		t.Errorf("error should not be nil when budget is exhausted")
+	}
+}
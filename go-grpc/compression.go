@@ -0,0 +1,59 @@
+package go_grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// CompressionUnaryServerInterceptor compresses responses with the registered
+// compressor name, e.g. gzip.Name, for clients that accept it.
+func CompressionUnaryServerInterceptor(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		setSendCompressor(ctx, name)
+		return handler(ctx, req)
+	}
+}
+
+func CompressionStreamServerInterceptor(name string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		setSendCompressor(ss.Context(), name)
+		return handler(srv, ss)
+	}
+}
+
+func setSendCompressor(ctx context.Context, name string) {
+	supported, err := grpc.ClientSupportedCompressors(ctx)
+	if err != nil {
+		return
+	}
+	for _, c := range supported {
+		if c == name {
+			_ = grpc.SetSendCompressor(ctx, name)
+			return
+		}
+	}
+}
+
+// ClientGzip compresses requests with gzip. Servers built with this package
+// always accept gzip requests.
+func ClientGzip() ClientConfigFunc {
+	return ClientDialOption(grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+}
+
+func (c *Config) serverOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(c.maxRecvMsgSize),
+		grpc.MaxSendMsgSize(c.maxSendMsgSize),
+	}
+	if c.maxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(c.maxConcurrentStreams))
+	}
+	if c.keepalive != nil {
+		opts = append(opts, grpc.KeepaliveParams(*c.keepalive))
+	}
+	if c.keepaliveEnforcement != nil {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(*c.keepaliveEnforcement))
+	}
+	return append(opts, c.serverOpts...)
+}
@@ -1,6 +1,13 @@
 package go_grpc
 
-import "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+import (
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"net"
+	"time"
+)
 
 const (
 	DefaultGRPCPort           = "5758"
@@ -10,6 +17,22 @@ const (
 	DefaultEnableCORS         = true
 	DefaultOnlyJSON           = true
 	DefaultRegisterReflection = true
+
+	DefaultEnableRPCMetrics         = true
+	DefaultEnableInFlightMetrics    = true
+	DefaultEnableMessageSizeMetrics = false
+	DefaultEnableHTTPMetrics        = true
+
+	DefaultEnableTracing = false
+
+	DefaultHealthCheckInterval = 10 * time.Second
+	DefaultHealthCheckTimeout  = 3 * time.Second
+
+	DefaultShutdownDrainPeriod = 0
+	DefaultShutdownTimeout     = 30 * time.Second
+
+	DefaultMaxRecvMsgSize = defaultMaxCallRcvMsgSize
+	DefaultMaxSendMsgSize = defaultMaxCallSendMsgSize
 )
 
 type Config struct {
@@ -23,6 +46,50 @@ type Config struct {
 	tls                bool
 	discardUnknown     bool
 	restServeMuxOpts   []runtime.ServeMuxOption
+
+	enableRPCMetrics         bool
+	enableInFlightMetrics    bool
+	enableMessageSizeMetrics bool
+	enableHTTPMetrics        bool
+
+	healthCheckInterval time.Duration
+	healthCheckTimeout  time.Duration
+
+	shutdownDrainPeriod time.Duration
+	shutdownTimeout     time.Duration
+
+	gRPCTLS           *TLSConfig
+	restTLS           *TLSConfig
+	prometheusTLS     *TLSConfig
+	tlsReloadInterval time.Duration
+
+	singlePort        string
+	singlePortMetrics bool
+
+	corsPolicy *CORSPolicy
+
+	accessLog *AccessLogConfig
+
+	enableTracing bool
+
+	defaultTimeout time.Duration
+	methodTimeouts MapMethodTimeouts
+
+	admin *AdminConfig
+
+	gatewayDialer func(ctx context.Context, addr string) (net.Conn, error)
+
+	maxRecvMsgSize       int
+	maxSendMsgSize       int
+	maxConcurrentStreams uint32
+	keepalive            *keepalive.ServerParameters
+	keepaliveEnforcement *keepalive.EnforcementPolicy
+	gzipLevel            *int
+	serverOpts           []grpc.ServerOption
+	panicHooks           []PanicHook
+
+	// errs of FromEnv and FromFile, reported by validate
+	errs []error
 }
 
 type ConfigFunc func(c *Config)
@@ -51,6 +118,8 @@ func PrometheusPort(p string) ConfigFunc {
 	}
 }
 
+// EnablePrometheus runs the metrics listener, or the metrics route of the
+// single port listener.
 func EnablePrometheus(p bool) ConfigFunc {
 	return func(c *Config) {
 		c.enablePrometheus = p
@@ -63,6 +132,19 @@ func EnableCORS(cors bool) ConfigFunc {
 	}
 }
 
+// CORS sets the policy applied to the REST and metrics servers while CORS is
+// enabled. A nil policy falls back to DefaultCORSPolicy.
+func CORS(p *CORSPolicy) ConfigFunc {
+	if p == nil {
+		p = DefaultCORSPolicy()
+	}
+	return func(c *Config) {
+		c.corsPolicy = p
+	}
+}
+
+// OnlyJSON limits the REST gateway to JSON. When false, requests and
+// responses in application/x-protobuf are served as well.
 func OnlyJSON(j bool) ConfigFunc {
 	return func(c *Config) {
 		c.onlyJSON = j
@@ -75,6 +157,8 @@ func RegisterReflection(r bool) ConfigFunc {
 	}
 }
 
+// WithTLS makes the REST gateway dial the gRPC listener over TLS. It is
+// implied by GRPCTLS.
 func WithTLS(tls bool) ConfigFunc {
 	return func(c *Config) {
 		c.tls = tls
@@ -93,6 +177,242 @@ func AddRestServerMuxOpt(opt ...runtime.ServeMuxOption) ConfigFunc {
 	}
 }
 
+func EnableRPCMetrics(e bool) ConfigFunc {
+	return func(c *Config) {
+		c.enableRPCMetrics = e
+	}
+}
+
+func EnableInFlightMetrics(e bool) ConfigFunc {
+	return func(c *Config) {
+		c.enableInFlightMetrics = e
+	}
+}
+
+func EnableMessageSizeMetrics(e bool) ConfigFunc {
+	return func(c *Config) {
+		c.enableMessageSizeMetrics = e
+	}
+}
+
+func EnableHTTPMetrics(e bool) ConfigFunc {
+	return func(c *Config) {
+		c.enableHTTPMetrics = e
+	}
+}
+
+func HealthCheckInterval(d time.Duration) ConfigFunc {
+	if d <= 0 {
+		d = DefaultHealthCheckInterval
+	}
+	return func(c *Config) {
+		c.healthCheckInterval = d
+	}
+}
+
+func HealthCheckTimeout(d time.Duration) ConfigFunc {
+	if d <= 0 {
+		d = DefaultHealthCheckTimeout
+	}
+	return func(c *Config) {
+		c.healthCheckTimeout = d
+	}
+}
+
+// ShutdownDrainPeriod is how long Shutdown keeps serving after health is
+// flipped to NOT_SERVING, giving load balancers time to stop routing.
+func ShutdownDrainPeriod(d time.Duration) ConfigFunc {
+	return func(c *Config) {
+		c.shutdownDrainPeriod = d
+	}
+}
+
+// ShutdownTimeout bounds Shutdown when the given context has no deadline.
+func ShutdownTimeout(d time.Duration) ConfigFunc {
+	if d <= 0 {
+		d = DefaultShutdownTimeout
+	}
+	return func(c *Config) {
+		c.shutdownTimeout = d
+	}
+}
+
+func GRPCTLS(t *TLSConfig) ConfigFunc {
+	return func(c *Config) {
+		c.gRPCTLS = t
+	}
+}
+
+func RESTTLS(t *TLSConfig) ConfigFunc {
+	return func(c *Config) {
+		c.restTLS = t
+	}
+}
+
+func PrometheusTLS(t *TLSConfig) ConfigFunc {
+	return func(c *Config) {
+		c.prometheusTLS = t
+	}
+}
+
+// TLSReloadInterval is how often the certificate files are checked for
+// changes during handshakes.
+func TLSReloadInterval(d time.Duration) ConfigFunc {
+	if d <= 0 {
+		d = DefaultTLSReloadInterval
+	}
+	return func(c *Config) {
+		c.tlsReloadInterval = d
+	}
+}
+
+// SinglePort serves gRPC and the REST gateway together on port p instead of
+// separate listeners. An empty port keeps the separate listeners.
+func SinglePort(p string) ConfigFunc {
+	return func(c *Config) {
+		c.singlePort = p
+	}
+}
+
+// SinglePortMetrics mounts /metrics, and the Admin endpoints, on the single
+// port listener instead of running the Prometheus listener.
+func SinglePortMetrics(m bool) ConfigFunc {
+	return func(c *Config) {
+		c.singlePortMetrics = m
+	}
+}
+
+// AccessLog logs one entry per gRPC call and gateway HTTP request. A nil
+// config, the default, disables the access log.
+func AccessLog(l *AccessLogConfig) ConfigFunc {
+	return func(c *Config) {
+		c.accessLog = l
+	}
+}
+
+// EnableTracing starts a span per gRPC call and gateway request and
+// propagates W3C trace context. Spans are only recorded once a go-trace
+// service is set up. Off by default, like tracing of the other packages.
+func EnableTracing(e bool) ConfigFunc {
+	return func(c *Config) {
+		c.enableTracing = e
+	}
+}
+
+// DefaultTimeout bounds every call without its own MethodTimeouts entry.
+// Zero, the default, leaves calls bounded by the client deadline only.
+func DefaultTimeout(d time.Duration) ConfigFunc {
+	return func(c *Config) {
+		c.defaultTimeout = d
+	}
+}
+
+// MethodTimeouts overrides DefaultTimeout per full method, e.g.
+// "/order.Service/Export". A zero timeout disables it for that method.
+func MethodTimeouts(m MapMethodTimeouts) ConfigFunc {
+	return func(c *Config) {
+		for method, d := range m {
+			c.methodTimeouts[method] = d
+		}
+	}
+}
+
+// Admin serves pprof, expvar and the admin endpoints on the metrics port,
+// behind the mandatory AdminConfig.Token. A nil config, the default, disables
+// them.
+func Admin(a *AdminConfig) ConfigFunc {
+	return func(c *Config) {
+		c.admin = a
+	}
+}
+
+// GatewayDialer makes the REST gateway reach the gRPC server through d
+// instead of the network, e.g. through a bufconn listener.
+func GatewayDialer(d func(ctx context.Context, addr string) (net.Conn, error)) ConfigFunc {
+	return func(c *Config) {
+		c.gatewayDialer = d
+	}
+}
+
+// MaxRecvMsgSize bounds the size of received messages, in bytes.
+func MaxRecvMsgSize(n int) ConfigFunc {
+	if n <= 0 {
+		n = DefaultMaxRecvMsgSize
+	}
+	return func(c *Config) {
+		c.maxRecvMsgSize = n
+	}
+}
+
+// MaxSendMsgSize bounds the size of sent messages, in bytes.
+func MaxSendMsgSize(n int) ConfigFunc {
+	if n <= 0 {
+		n = DefaultMaxSendMsgSize
+	}
+	return func(c *Config) {
+		c.maxSendMsgSize = n
+	}
+}
+
+// MaxConcurrentStreams caps the concurrent streams of each connection. Zero,
+// the default, leaves it to gRPC.
+func MaxConcurrentStreams(n uint32) ConfigFunc {
+	return func(c *Config) {
+		c.maxConcurrentStreams = n
+	}
+}
+
+// ServerKeepalive replaces the keepalive parameters, including those set by
+// MaxConnectionAge.
+func ServerKeepalive(p keepalive.ServerParameters) ConfigFunc {
+	return func(c *Config) {
+		c.keepalive = &p
+	}
+}
+
+// MaxConnectionAge closes connections after age, letting in-flight calls
+// finish within grace, so clients reconnect and load balancers rebalance.
+func MaxConnectionAge(age, grace time.Duration) ConfigFunc {
+	return func(c *Config) {
+		if c.keepalive == nil {
+			c.keepalive = &keepalive.ServerParameters{}
+		}
+		c.keepalive.MaxConnectionAge = age
+		c.keepalive.MaxConnectionAgeGrace = grace
+	}
+}
+
+// KeepaliveEnforcement closes connections of clients pinging more often
+// than the policy allows.
+func KeepaliveEnforcement(p keepalive.EnforcementPolicy) ConfigFunc {
+	return func(c *Config) {
+		c.keepaliveEnforcement = &p
+	}
+}
+
+// Gzip compresses responses to clients accepting gzip at level, from
+// gzip.BestSpeed to gzip.BestCompression or gzip.DefaultCompression.
+// Compressed requests are accepted regardless.
+func Gzip(level int) ConfigFunc {
+	return func(c *Config) {
+		c.gzipLevel = &level
+	}
+}
+
+func AddServerOpt(opt ...grpc.ServerOption) ConfigFunc {
+	return func(c *Config) {
+		c.serverOpts = append(c.serverOpts, opt...)
+	}
+}
+
+// PanicHooks are called for every panic recovered from gRPC calls and REST
+// handlers.
+func PanicHooks(hooks ...PanicHook) ConfigFunc {
+	return func(c *Config) {
+		c.panicHooks = append(c.panicHooks, hooks...)
+	}
+}
+
 func generate(args ...ConfigFunc) *Config {
 	c := &Config{
 		gRPCPort:           DefaultGRPCPort,
@@ -102,6 +422,28 @@ func generate(args ...ConfigFunc) *Config {
 		enableCORS:         DefaultEnableCORS,
 		onlyJSON:           DefaultOnlyJSON,
 		registerReflection: DefaultRegisterReflection,
+
+		enableRPCMetrics:         DefaultEnableRPCMetrics,
+		enableInFlightMetrics:    DefaultEnableInFlightMetrics,
+		enableMessageSizeMetrics: DefaultEnableMessageSizeMetrics,
+		enableHTTPMetrics:        DefaultEnableHTTPMetrics,
+
+		healthCheckInterval: DefaultHealthCheckInterval,
+		healthCheckTimeout:  DefaultHealthCheckTimeout,
+
+		shutdownDrainPeriod: DefaultShutdownDrainPeriod,
+		shutdownTimeout:     DefaultShutdownTimeout,
+
+		tlsReloadInterval: DefaultTLSReloadInterval,
+
+		corsPolicy: DefaultCORSPolicy(),
+
+		enableTracing: DefaultEnableTracing,
+
+		methodTimeouts: make(MapMethodTimeouts),
+
+		maxRecvMsgSize: DefaultMaxRecvMsgSize,
+		maxSendMsgSize: DefaultMaxSendMsgSize,
 	}
 	for i := range args {
 		args[i](c)
@@ -3,56 +3,410 @@ package go_grpc
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 var (
 	ErrUnsupportedNetwork = fmt.Errorf("[ERROR]: Unsupported network")
+	ErrInvalidClientCA    = fmt.Errorf("[ERROR]: Client CA contains no certificate")
 )
 
 const (
 	defaultMaxCallRcvMsgSize  = 1024 * 1024 * 50 //50MB
 	defaultMaxCallSendMsgSize = 1024 * 1024 * 50 //50MB
+
+	DefaultClientNetwork             = "tcp"
+	DefaultClientLoadBalancingPolicy = "round_robin"
 )
 
+// ClientTLSConfig configures TLS towards a downstream. CAFile replaces the
+// system roots, and CertFile/KeyFile present a client certificate for mTLS.
+type ClientTLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// RetryPolicy is the gRPC service config retry policy applied to every
+// method of the downstream.
+type RetryPolicy struct {
+	MaxAttempts          int
+	InitialBackoff       time.Duration
+	MaxBackoff           time.Duration
+	BackoffMultiplier    float64
+	RetryableStatusCodes []codes.Code
+}
+
+type ClientConfig struct {
+	network             string
+	loadBalancingPolicy string
+	maxCallRecvMsgSize  int
+	maxCallSendMsgSize  int
+	keepalive           *keepalive.ClientParameters
+	connectTimeout      time.Duration
+	defaultTimeout      time.Duration
+	retryPolicy         *RetryPolicy
+	tls                 *ClientTLSConfig
+	unaryInterceptors   []grpc.UnaryClientInterceptor
+	streamInterceptors  []grpc.StreamClientInterceptor
+	dialOpts            []grpc.DialOption
+}
+
+type ClientConfigFunc func(c *ClientConfig)
+
+// ClientNetwork is "tcp" or "unix".
+func ClientNetwork(n string) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.network = n
+	}
+}
+
+func ClientLoadBalancingPolicy(p string) ClientConfigFunc {
+	if p == "" {
+		p = DefaultClientLoadBalancingPolicy
+	}
+	return func(c *ClientConfig) {
+		c.loadBalancingPolicy = p
+	}
+}
+
+func ClientMaxCallRecvMsgSize(s int) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.maxCallRecvMsgSize = s
+	}
+}
+
+func ClientMaxCallSendMsgSize(s int) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.maxCallSendMsgSize = s
+	}
+}
+
+func ClientKeepalive(p keepalive.ClientParameters) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.keepalive = &p
+	}
+}
+
+// ClientConnectTimeout makes the dial block until the connection is ready or
+// the timeout expires.
+func ClientConnectTimeout(d time.Duration) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.connectTimeout = d
+	}
+}
+
+// ClientDefaultTimeout is applied to unary calls whose context has no deadline.
+func ClientDefaultTimeout(d time.Duration) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.defaultTimeout = d
+	}
+}
+
+func ClientRetry(p *RetryPolicy) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.retryPolicy = p
+	}
+}
+
+// ClientTLS dials over TLS. A nil config dials insecure.
+func ClientTLS(t *ClientTLSConfig) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.tls = t
+	}
+}
+
+func ClientUnaryInterceptor(i ...grpc.UnaryClientInterceptor) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.unaryInterceptors = append(c.unaryInterceptors, i...)
+	}
+}
+
+func ClientStreamInterceptor(i ...grpc.StreamClientInterceptor) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.streamInterceptors = append(c.streamInterceptors, i...)
+	}
+}
+
+func ClientDialOption(opt ...grpc.DialOption) ClientConfigFunc {
+	return func(c *ClientConfig) {
+		c.dialOpts = append(c.dialOpts, opt...)
+	}
+}
+
+func generateClientConfig(args ...ClientConfigFunc) *ClientConfig {
+	c := &ClientConfig{
+		network:             DefaultClientNetwork,
+		loadBalancingPolicy: DefaultClientLoadBalancingPolicy,
+		maxCallRecvMsgSize:  defaultMaxCallRcvMsgSize,
+		maxCallSendMsgSize:  defaultMaxCallSendMsgSize,
+	}
+	for i := range args {
+		args[i](c)
+	}
+	return c
+}
+
+func (c *ClientConfig) target(addr string) (string, error) {
+	switch c.network {
+	case "tcp":
+		return addr, nil
+	case "unix":
+		if strings.HasPrefix(addr, "unix:") {
+			return addr, nil
+		}
+		return "unix:" + addr, nil
+	default:
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedNetwork, c.network)
+	}
+}
+
+func (c *ClientConfig) credentials() (credentials.TransportCredentials, error) {
+	if c.tls == nil {
+		return insecure.NewCredentials(), nil
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.tls.ServerName,
+		InsecureSkipVerify: c.tls.InsecureSkipVerify,
+	}
+	if c.tls.CAFile != "" {
+		pem, err := os.ReadFile(c.tls.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidClientCA, c.tls.CAFile)
+		}
+	}
+	if c.tls.CertFile != "" || c.tls.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.tls.CertFile, c.tls.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+func (c *ClientConfig) serviceConfig() (string, error) {
+	type retryPolicy struct {
+		MaxAttempts          int          `json:"maxAttempts"`
+		InitialBackoff       string       `json:"initialBackoff"`
+		MaxBackoff           string       `json:"maxBackoff"`
+		BackoffMultiplier    float64      `json:"backoffMultiplier"`
+		RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+	}
+	type methodConfig struct {
+		Name        []struct{}   `json:"name"`
+		RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+	}
+	sc := struct {
+		LoadBalancingPolicy string         `json:"loadBalancingPolicy,omitempty"`
+		MethodConfig        []methodConfig `json:"methodConfig,omitempty"`
+	}{
+		LoadBalancingPolicy: c.loadBalancingPolicy,
+	}
+	if p := c.retryPolicy; p != nil {
+		rp := &retryPolicy{
+			MaxAttempts:          p.MaxAttempts,
+			InitialBackoff:       fmt.Sprintf("%.3fs", p.InitialBackoff.Seconds()),
+			MaxBackoff:           fmt.Sprintf("%.3fs", p.MaxBackoff.Seconds()),
+			BackoffMultiplier:    p.BackoffMultiplier,
+			RetryableStatusCodes: p.RetryableStatusCodes,
+		}
+		sc.MethodConfig = append(sc.MethodConfig, methodConfig{Name: []struct{}{{}}, RetryPolicy: rp})
+	}
+	b, err := json.Marshal(sc)
+	return string(b), err
+}
+
+func (c *ClientConfig) dialOptions() ([]grpc.DialOption, error) {
+	creds, err := c.credentials()
+	if err != nil {
+		return nil, err
+	}
+	serviceConfig, err := c.serviceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(c.maxCallRecvMsgSize),
+			grpc.MaxCallSendMsgSize(c.maxCallSendMsgSize),
+		),
+	}
+	if c.keepalive != nil {
+		opts = append(opts, grpc.WithKeepaliveParams(*c.keepalive))
+	}
+	if c.connectTimeout > 0 {
+		opts = append(opts, grpc.WithBlock())
+	}
+
+	unary := c.unaryInterceptors
+	if c.defaultTimeout > 0 {
+		unary = append([]grpc.UnaryClientInterceptor{DefaultTimeoutUnaryClientInterceptor(c.defaultTimeout)}, unary...)
+	}
+	if len(unary) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(unary...))
+	}
+	if len(c.streamInterceptors) > 0 {
+		opts = append(opts, grpc.WithChainStreamInterceptor(c.streamInterceptors...))
+	}
+
+	return append(opts, c.dialOpts...), nil
+}
+
+// NewClientConn dials addr with the given client config.
+func NewClientConn(addr string, args ...ClientConfigFunc) (*grpc.ClientConn, error) {
+	return NewClientConnWithCtx(context.Background(), addr, args...)
+}
+
+func NewClientConnWithCtx(ctx context.Context, addr string, args ...ClientConfigFunc) (*grpc.ClientConn, error) {
+	cfg := generateClientConfig(args...)
+	target, err := cfg.target(addr)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := cfg.dialOptions()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.connectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.connectTimeout)
+		defer cancel()
+	}
+	return grpc.DialContext(ctx, target, opts...)
+}
+
+// ClientConn dials addr, over TLS with the system roots when s is true.
+//
+// Deprecated: use NewClientConn, which returns errors instead of panicking.
 func ClientConn(addr string, s ...bool) grpc.ClientConnInterface {
-	opts := getDialOpts(isSecure(s...))
-	conn, err := grpc.Dial(addr, opts...)
+	var args []ClientConfigFunc
+	if isSecure(s...) {
+		args = append(args, ClientTLS(&ClientTLSConfig{}))
+	}
+	conn, err := NewClientConn(addr, args...)
 	if err != nil {
 		panic(err)
 	}
 	return conn
 }
 
-func getDialOpts(s bool) []grpc.DialOption {
-	creds := insecure.NewCredentials()
-	if s {
-		creds = credentials.NewTLS(&tls.Config{})
+// DefaultTimeoutUnaryClientInterceptor sets timeout on calls without a deadline.
+func DefaultTimeoutUnaryClientInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
 	}
-	return []grpc.DialOption{
-		grpc.WithTransportCredentials(creds),
-		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy":"round_robin"}`),
-		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(defaultMaxCallRcvMsgSize),
-			grpc.MaxCallSendMsgSize(defaultMaxCallSendMsgSize),
-		),
+}
+
+// ClientConnCache shares one connection per target, so services talking to
+// the same downstream reuse it. The config of the first Get for a target wins.
+type ClientConnCache struct {
+	mu      sync.Mutex
+	conns   map[string]*grpc.ClientConn
+	dialing map[string]*clientConnDial
+}
+
+// clientConnDial is a dial in progress, concurrent Gets for its target wait
+// for it instead of dialing again.
+type clientConnDial struct {
+	done chan struct{}
+	conn *grpc.ClientConn
+	err  error
+}
+
+func NewClientConnCache() *ClientConnCache {
+	return &ClientConnCache{
+		conns:   make(map[string]*grpc.ClientConn),
+		dialing: make(map[string]*clientConnDial),
 	}
 }
 
-func dial(network, addr string) (*grpc.ClientConn, error) {
-	switch network {
-	case "tcp":
-		return dialTCP(context.Background(), addr)
-	default:
-		return nil, fmt.Errorf("%w: %v", ErrUnsupportedNetwork, network)
+// Get returns the connection to addr, dialing it on first use. Dialing runs
+// outside the lock, so a slow target does not block Gets for other targets.
+func (c *ClientConnCache) Get(addr string, args ...ClientConfigFunc) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	if conn, ok := c.conns[addr]; ok {
+		c.mu.Unlock()
+		return conn, nil
+	}
+	if d, ok := c.dialing[addr]; ok {
+		c.mu.Unlock()
+		<-d.done
+		return d.conn, d.err
+	}
+	d := &clientConnDial{done: make(chan struct{})}
+	c.dialing[addr] = d
+	c.mu.Unlock()
+
+	conn, err := NewClientConn(addr, args...)
+
+	c.mu.Lock()
+	delete(c.dialing, addr)
+	if err == nil {
+		if existing, ok := c.conns[addr]; ok {
+			_ = conn.Close()
+			conn = existing
+		} else {
+			c.conns[addr] = conn
+		}
 	}
+	c.mu.Unlock()
+
+	d.conn, d.err = conn, err
+	close(d.done)
+	return conn, err
+}
+
+func (c *ClientConnCache) CloseAll() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for addr, conn := range c.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", addr, err))
+		}
+		delete(c.conns, addr)
+	}
+	return errors.Join(errs...)
+}
+
+var defaultClientConnCache = NewClientConnCache()
+
+// GetClientConn returns the shared connection to addr from the default cache.
+func GetClientConn(addr string, args ...ClientConfigFunc) (*grpc.ClientConn, error) {
+	return defaultClientConnCache.Get(addr, args...)
 }
 
-func dialTCP(ctx context.Context, addr string, s ...bool) (*grpc.ClientConn, error) {
-	return grpc.DialContext(ctx, addr, getDialOpts(isSecure(s...))...)
+// CloseAllClientConns closes every connection in the default cache.
+func CloseAllClientConns() error {
+	return defaultClientConnCache.CloseAll()
 }
 
 func isSecure(s ...bool) bool {
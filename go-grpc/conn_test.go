@@ -0,0 +1,111 @@
+package go_grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewClientConnRetryPolicy(t *testing.T) {
+	cache := NewClientConnCache()
+	defer func() {
+		if err := cache.CloseAll(); err != nil {
+			t.Errorf("unexpected close error: %v", err)
+		}
+	}()
+
+	args := []ClientConfigFunc{
+		ClientDefaultTimeout(time.Second),
+		ClientRetry(&RetryPolicy{
+			MaxAttempts:          3,
+			InitialBackoff:       100 * time.Millisecond,
+			MaxBackoff:           time.Second,
+			BackoffMultiplier:    2,
+			RetryableStatusCodes: []codes.Code{codes.Unavailable},
+		}),
+	}
+	first, err := cache.Get("127.0.0.1:5758", args...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := cache.Get("127.0.0.1:5758")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("connection to the same target should be shared")
+	}
+}
+
+func TestClientConnCacheConcurrentGet(t *testing.T) {
+	cache := NewClientConnCache()
+	defer func() { _ = cache.CloseAll() }()
+
+	conns := make([]*grpc.ClientConn, 8)
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, err := cache.Get("127.0.0.1:5758")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			conns[i] = conn
+		}(i)
+	}
+	wg.Wait()
+	for _, conn := range conns[1:] {
+		if conn != conns[0] {
+			t.Fatalf("concurrent gets should share one connection")
+		}
+	}
+}
+
+func TestNewClientConnUnsupportedNetwork(t *testing.T) {
+	if _, err := NewClientConn("127.0.0.1:5758", ClientNetwork("udp")); err == nil {
+		t.Errorf("error should not be nil")
+	}
+}
+
+func TestGatewayClientConnMessageSize(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	s := NewService(
+		MaxSendMsgSize(8<<20),
+		GatewayDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	).(*service)
+	s.Init()
+	s.GetServer().RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Large",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Get",
+			Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				if err := dec(&emptypb.Empty{}); err != nil {
+					return nil, err
+				}
+				return wrapperspb.Bytes(make([]byte, 5<<20)), nil
+			},
+		}},
+	}, struct{}{})
+	go func() { _ = s.GetServer().Serve(lis) }()
+	defer s.GetServer().Stop()
+	defer s.closeGatewayConn()
+
+	resp := &wrapperspb.BytesValue{}
+	if err := s.gatewayClientConn().Invoke(context.Background(), "/test.Large/Get", &emptypb.Empty{}, resp); err != nil {
+		t.Fatalf("gateway should receive messages up to the send size of the server, got %v", err)
+	}
+	if len(resp.Value) != 5<<20 {
+		t.Errorf("unexpected response size %d", len(resp.Value))
+	}
+}
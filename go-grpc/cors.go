@@ -0,0 +1,147 @@
+package go_grpc
+
+import (
+	"net/http"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CORSAllowAll = "*"
+
+	headerOrigin                        = "Origin"
+	headerVary                          = "Vary"
+	headerAccessControlRequestMethod    = "Access-Control-Request-Method"
+	headerAccessControlRequestHeaders   = "Access-Control-Request-Headers"
+	headerAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	headerAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	headerAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	headerAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	headerAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	headerAccessControlMaxAge           = "Access-Control-Max-Age"
+)
+
+// CORSPolicy decides which browser origins may call the REST and metrics
+// servers. AllowedOrigins entries are exact origins ("https://app.example.com"),
+// wildcard subdomains ("https://*.example.com") or CORSAllowAll.
+type CORSPolicy struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and auth headers. It can
+	// not be combined with CORSAllowAll, as every site could then make
+	// authenticated calls.
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// DefaultCORSPolicy allows any origin without credentials, along with the
+// headers forwarded by MuxIncomingHeaderMatcher.
+func DefaultCORSPolicy() *CORSPolicy {
+	return &CORSPolicy{
+		AllowedOrigins: []string{CORSAllowAll},
+		AllowedMethods: []string{
+			http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		},
+		AllowedHeaders: []string{
+			HeaderContentType, HeaderAccept, HeaderAuthorization, HeaderAcceptLanguage, gotex.RequestHeaderKeyRequestID,
+			HeaderIdempotencyKey,
+		},
+	}
+}
+
+func (p *CORSPolicy) allowsAllOrigins() bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == CORSAllowAll {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) isOriginAllowed(origin string) bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == CORSAllowAll || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok {
+			origin := strings.ToLower(origin)
+			prefix, suffix = strings.ToLower(prefix), strings.ToLower(suffix)
+			if len(origin) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(origin, prefix) &&
+				strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) allowedHeaders(r *http.Request) string {
+	for _, h := range p.AllowedHeaders {
+		if h == CORSAllowAll {
+			return r.Header.Get(headerAccessControlRequestHeaders)
+		}
+	}
+	return strings.Join(p.AllowedHeaders, ",")
+}
+
+// Handler applies the policy in front of h. Preflight requests from allowed
+// origins are answered directly and never reach h.
+func (p *CORSPolicy) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get(headerOrigin)
+		if origin == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add(headerVary, headerOrigin)
+		preflight := r.Method == http.MethodOptions && r.Header.Get(headerAccessControlRequestMethod) != ""
+		if !p.isOriginAllowed(origin) {
+			if preflight {
+				gologger.Warnf("preflight request for %s from disallowed origin %s", r.URL.Path, origin)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set(headerAccessControlAllowOrigin, origin)
+		if p.AllowCredentials {
+			w.Header().Set(headerAccessControlAllowCredentials, "true")
+		}
+
+		if !preflight {
+			if len(p.ExposedHeaders) > 0 {
+				w.Header().Set(headerAccessControlExposeHeaders, strings.Join(p.ExposedHeaders, ","))
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add(headerVary, headerAccessControlRequestMethod)
+		w.Header().Add(headerVary, headerAccessControlRequestHeaders)
+		w.Header().Set(headerAccessControlAllowMethods, strings.Join(p.AllowedMethods, ","))
+		if headers := p.allowedHeaders(r); headers != "" {
+			w.Header().Set(headerAccessControlAllowHeaders, headers)
+		}
+		if p.MaxAge > 0 {
+			w.Header().Set(headerAccessControlMaxAge, strconv.Itoa(int(p.MaxAge.Seconds())))
+		}
+		gologger.Debugf("preflight request for %s", r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (s *service) corsHandler(h http.Handler) http.Handler {
+	if !s.cfg.enableCORS {
+		return h
+	}
+	return s.cfg.corsPolicy.Handler(h)
+}
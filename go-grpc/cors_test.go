@@ -0,0 +1,79 @@
+package go_grpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORSPolicyHandler(t *testing.T) {
+	policy := &CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{HeaderContentType, HeaderAcceptLanguage},
+		ExposedHeaders:   []string{"RequestID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := policy.Handler(next)
+
+	testCases := []struct {
+		name           string
+		method         string
+		origin         string
+		preflight      bool
+		expectedStatus int
+		expectedOrigin string
+	}{
+		{name: "exact origin", method: http.MethodGet, origin: "https://app.example.com", expectedStatus: http.StatusOK, expectedOrigin: "https://app.example.com"},
+		{name: "wildcard subdomain", method: http.MethodGet, origin: "https://api.example.org", expectedStatus: http.StatusOK, expectedOrigin: "https://api.example.org"},
+		{name: "wildcard apex not allowed", method: http.MethodGet, origin: "https://example.org", expectedStatus: http.StatusOK},
+		{name: "unknown origin", method: http.MethodGet, origin: "https://evil.com", expectedStatus: http.StatusOK},
+		{name: "preflight allowed", method: http.MethodOptions, origin: "https://app.example.com", preflight: true, expectedStatus: http.StatusNoContent, expectedOrigin: "https://app.example.com"},
+		{name: "preflight disallowed", method: http.MethodOptions, origin: "https://evil.com", preflight: true, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/v1/users", nil)
+			r.Header.Set(headerOrigin, tt.origin)
+			if tt.preflight {
+				r.Header.Set(headerAccessControlRequestMethod, http.MethodPost)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status should be %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if got := w.Header().Get(headerAccessControlAllowOrigin); got != tt.expectedOrigin {
+				t.Errorf("allow origin should be '%s', got '%s'", tt.expectedOrigin, got)
+			}
+			if tt.preflight && tt.expectedOrigin != "" {
+				if got := w.Header().Get(headerAccessControlAllowHeaders); got != "Content-Type,accept-language" {
+					t.Errorf("allow headers should be 'Content-Type,accept-language', got '%s'", got)
+				}
+				if got := w.Header().Get(headerAccessControlMaxAge); got != "600" {
+					t.Errorf("max age should be '600', got '%s'", got)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultCORSPolicyIdempotencyKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodOptions, "/v1/orders", nil)
+	r.Header.Set(headerOrigin, "https://app.example.com")
+	r.Header.Set(headerAccessControlRequestMethod, http.MethodPost)
+	w := httptest.NewRecorder()
+	MuxCORS(http.NotFoundHandler()).ServeHTTP(w, r)
+
+	if got := w.Header().Get(headerAccessControlAllowHeaders); !strings.Contains(got, HeaderIdempotencyKey) {
+		t.Errorf("preflight should allow %s, got '%s'", HeaderIdempotencyKey, got)
+	}
+}
@@ -0,0 +1,68 @@
+package go_grpc
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"mime"
+	"strings"
+)
+
+const (
+	HeaderContentDisposition = "Content-Disposition"
+)
+
+var (
+	mapOutgoingHeaderTransform = map[string]string{
+		strings.ToLower(HeaderContentDisposition): HeaderContentDisposition,
+	}
+)
+
+// SetContentDisposition makes the gateway serve the response, e.g. a
+// google.api.HttpBody, as an attachment named filename. Streaming RPCs must
+// call it before sending the first message.
+func SetContentDisposition(ctx context.Context, filename string) error {
+	v := mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	return grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(HeaderContentDisposition), v))
+}
+
+func MuxOutgoingHeaderMatcher(key string) (string, bool) {
+	if h, ok := mapOutgoingHeaderTransform[strings.ToLower(key)]; ok {
+		return h, true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// httpBodyMarshaler writes google.api.HttpBody messages as is, with their
+// own content type. Server-streamed HttpBody chunks are written back to
+// back, while other streamed messages stay newline delimited.
+type httpBodyMarshaler struct {
+	*runtime.HTTPBodyMarshaler
+}
+
+func newHTTPBodyMarshaler(m runtime.Marshaler) runtime.Marshaler {
+	return &httpBodyMarshaler{
+		HTTPBodyMarshaler: &runtime.HTTPBodyMarshaler{Marshaler: m},
+	}
+}
+
+func (m *httpBodyMarshaler) Marshal(v interface{}) ([]byte, error) {
+	b, err := m.HTTPBodyMarshaler.Marshal(v)
+	// streamed messages are wrapped in a result map by the gateway
+	if _, ok := v.(map[string]interface{}); ok && err == nil {
+		b = append(b, m.delimiter()...)
+	}
+	return b, err
+}
+
+func (m *httpBodyMarshaler) Delimiter() []byte {
+	return nil
+}
+
+func (m *httpBodyMarshaler) delimiter() []byte {
+	if d, ok := m.HTTPBodyMarshaler.Marshaler.(runtime.Delimited); ok {
+		return d.Delimiter()
+	}
+	return []byte("\n")
+}
@@ -0,0 +1,185 @@
+package go_grpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"google.golang.org/grpc/keepalive"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	EnvConfigFile = "GRPC_CONFIG_FILE"
+)
+
+// ConfigSpec is the part of Config loaded from GRPC_* env vars and config
+// files. Unset fields keep the value of the Config they are applied to.
+type ConfigSpec struct {
+	GRPCPort                 *string        `envconfig:"GRPC_PORT" yaml:"grpc_port"`
+	RESTPort                 *string        `envconfig:"GRPC_REST_PORT" yaml:"rest_port"`
+	PrometheusPort           *string        `envconfig:"GRPC_PROMETHEUS_PORT" yaml:"prometheus_port"`
+	SinglePort               *string        `envconfig:"GRPC_SINGLE_PORT" yaml:"single_port"`
+	SinglePortMetrics        *bool          `envconfig:"GRPC_SINGLE_PORT_METRICS" yaml:"single_port_metrics"`
+	EnablePrometheus         *bool          `envconfig:"GRPC_ENABLE_PROMETHEUS" yaml:"enable_prometheus"`
+	EnableCORS               *bool          `envconfig:"GRPC_ENABLE_CORS" yaml:"enable_cors"`
+	OnlyJSON                 *bool          `envconfig:"GRPC_ONLY_JSON" yaml:"only_json"`
+	RegisterReflection       *bool          `envconfig:"GRPC_REGISTER_REFLECTION" yaml:"register_reflection"`
+	TLS                      *bool          `envconfig:"GRPC_TLS" yaml:"tls"`
+	DiscardUnknown           *bool          `envconfig:"GRPC_DISCARD_UNKNOWN" yaml:"discard_unknown"`
+	EnableRPCMetrics         *bool          `envconfig:"GRPC_ENABLE_RPC_METRICS" yaml:"enable_rpc_metrics"`
+	EnableInFlightMetrics    *bool          `envconfig:"GRPC_ENABLE_IN_FLIGHT_METRICS" yaml:"enable_in_flight_metrics"`
+	EnableMessageSizeMetrics *bool          `envconfig:"GRPC_ENABLE_MESSAGE_SIZE_METRICS" yaml:"enable_message_size_metrics"`
+	EnableHTTPMetrics        *bool          `envconfig:"GRPC_ENABLE_HTTP_METRICS" yaml:"enable_http_metrics"`
+	EnableTracing            *bool          `envconfig:"GRPC_ENABLE_TRACING" yaml:"enable_tracing"`
+	AccessLog                *bool          `envconfig:"GRPC_ACCESS_LOG" yaml:"access_log"`
+	HealthCheckInterval      *time.Duration `envconfig:"GRPC_HEALTH_CHECK_INTERVAL" yaml:"health_check_interval"`
+	HealthCheckTimeout       *time.Duration `envconfig:"GRPC_HEALTH_CHECK_TIMEOUT" yaml:"health_check_timeout"`
+	ShutdownDrainPeriod      *time.Duration `envconfig:"GRPC_SHUTDOWN_DRAIN_PERIOD" yaml:"shutdown_drain_period"`
+	ShutdownTimeout          *time.Duration `envconfig:"GRPC_SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout"`
+	TLSReloadInterval        *time.Duration `envconfig:"GRPC_TLS_RELOAD_INTERVAL" yaml:"tls_reload_interval"`
+	DefaultTimeout           *time.Duration `envconfig:"GRPC_DEFAULT_TIMEOUT" yaml:"default_timeout"`
+	MaxRecvMsgSize           *int           `envconfig:"GRPC_MAX_RECV_MSG_SIZE" yaml:"max_recv_msg_size"`
+	MaxSendMsgSize           *int           `envconfig:"GRPC_MAX_SEND_MSG_SIZE" yaml:"max_send_msg_size"`
+	MaxConcurrentStreams     *uint32        `envconfig:"GRPC_MAX_CONCURRENT_STREAMS" yaml:"max_concurrent_streams"`
+	MaxConnectionAge         *time.Duration `envconfig:"GRPC_MAX_CONNECTION_AGE" yaml:"max_connection_age"`
+	MaxConnectionAgeGrace    *time.Duration `envconfig:"GRPC_MAX_CONNECTION_AGE_GRACE" yaml:"max_connection_age_grace"`
+	Gzip                     *int           `envconfig:"GRPC_GZIP" yaml:"gzip"`
+	// TLS configs are only applied when a certificate file is set.
+	GRPCTLS       *TLSConfig `envconfig:"GRPC_GRPC_TLS" yaml:"grpc_tls"`
+	RESTTLS       *TLSConfig `envconfig:"GRPC_REST_TLS" yaml:"rest_tls"`
+	PrometheusTLS *TLSConfig `envconfig:"GRPC_PROMETHEUS_TLS" yaml:"prometheus_tls"`
+	// AdminToken enables the Admin endpoints protected by the token.
+	AdminToken *string `envconfig:"GRPC_ADMIN_TOKEN" yaml:"admin_token"`
+}
+
+// FromEnv applies the GRPC_* env vars, on top of the config file named by
+// GRPC_CONFIG_FILE if set. Errors are reported by Service.Validate.
+func FromEnv() ConfigFunc {
+	return func(c *Config) {
+		if path := os.Getenv(EnvConfigFile); path != "" {
+			FromFile(path)(c)
+		}
+		spec := &ConfigSpec{}
+		if err := envconfig.Process("", spec); err != nil {
+			c.errs = append(c.errs, fmt.Errorf("%w: env: %v", ErrInvalidConfig, err))
+			return
+		}
+		spec.apply(c)
+	}
+}
+
+// FromFile applies the YAML or JSON config file at path, with the keys of
+// ConfigSpec. Errors are reported by Service.Validate.
+func FromFile(path string) ConfigFunc {
+	return func(c *Config) {
+		spec, err := readConfigFile(path)
+		if err != nil {
+			c.errs = append(c.errs, fmt.Errorf("%w: file %s: %v", ErrInvalidConfig, path, err))
+			return
+		}
+		spec.apply(c)
+	}
+}
+
+func readConfigFile(path string) (*ConfigSpec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec := &ConfigSpec{}
+	// YAML is a superset of JSON, so both are decoded the same way
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err = dec.Decode(spec); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func (s *ConfigSpec) apply(c *Config) {
+	setString(&c.gRPCPort, s.GRPCPort)
+	setString(&c.restPort, s.RESTPort)
+	setString(&c.prometheusPort, s.PrometheusPort)
+	setString(&c.singlePort, s.SinglePort)
+	setBool(&c.singlePortMetrics, s.SinglePortMetrics)
+	setBool(&c.enablePrometheus, s.EnablePrometheus)
+	setBool(&c.enableCORS, s.EnableCORS)
+	setBool(&c.onlyJSON, s.OnlyJSON)
+	setBool(&c.registerReflection, s.RegisterReflection)
+	setBool(&c.tls, s.TLS)
+	setBool(&c.discardUnknown, s.DiscardUnknown)
+	setBool(&c.enableRPCMetrics, s.EnableRPCMetrics)
+	setBool(&c.enableInFlightMetrics, s.EnableInFlightMetrics)
+	setBool(&c.enableMessageSizeMetrics, s.EnableMessageSizeMetrics)
+	setBool(&c.enableHTTPMetrics, s.EnableHTTPMetrics)
+	setBool(&c.enableTracing, s.EnableTracing)
+	setDuration(&c.healthCheckInterval, s.HealthCheckInterval)
+	setDuration(&c.healthCheckTimeout, s.HealthCheckTimeout)
+	setDuration(&c.shutdownDrainPeriod, s.ShutdownDrainPeriod)
+	setDuration(&c.shutdownTimeout, s.ShutdownTimeout)
+	setDuration(&c.tlsReloadInterval, s.TLSReloadInterval)
+	setDuration(&c.defaultTimeout, s.DefaultTimeout)
+
+	if s.MaxRecvMsgSize != nil {
+		c.maxRecvMsgSize = *s.MaxRecvMsgSize
+	}
+	if s.MaxSendMsgSize != nil {
+		c.maxSendMsgSize = *s.MaxSendMsgSize
+	}
+	if s.MaxConcurrentStreams != nil {
+		c.maxConcurrentStreams = *s.MaxConcurrentStreams
+	}
+	if s.MaxConnectionAge != nil || s.MaxConnectionAgeGrace != nil {
+		if c.keepalive == nil {
+			c.keepalive = &keepalive.ServerParameters{}
+		}
+		setDuration(&c.keepalive.MaxConnectionAge, s.MaxConnectionAge)
+		setDuration(&c.keepalive.MaxConnectionAgeGrace, s.MaxConnectionAgeGrace)
+	}
+	if s.Gzip != nil {
+		c.gzipLevel = s.Gzip
+	}
+	if s.AccessLog != nil {
+		c.accessLog = nil
+		if *s.AccessLog {
+			c.accessLog = DefaultAccessLogConfig()
+		}
+	}
+	if s.GRPCTLS != nil && s.GRPCTLS.CertFile != "" {
+		c.gRPCTLS = s.GRPCTLS
+	}
+	if s.RESTTLS != nil && s.RESTTLS.CertFile != "" {
+		c.restTLS = s.RESTTLS
+	}
+	if s.PrometheusTLS != nil && s.PrometheusTLS.CertFile != "" {
+		c.prometheusTLS = s.PrometheusTLS
+	}
+	if s.AdminToken != nil {
+		if c.admin == nil {
+			c.admin = &AdminConfig{}
+		}
+		c.admin.Token = *s.AdminToken
+	}
+}
+
+func setString(dst *string, v *string) {
+	if v != nil && *v != "" {
+		*dst = *v
+	}
+}
+
+func setBool(dst *bool, v *bool) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func setDuration(dst *time.Duration, v *time.Duration) {
+	if v != nil {
+		*dst = *v
+	}
+}
@@ -0,0 +1,107 @@
+package go_grpc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestFromFileAndEnv(t *testing.T) {
+	path := writeConfigFile(t, "grpc.yaml", `
+grpc_port: "6000"
+rest_port: "6001"
+enable_prometheus: false
+health_check_interval: 20s
+grpc_tls:
+  cert_file: /etc/tls/tls.crt
+  key_file: /etc/tls/tls.key
+`)
+	t.Setenv(EnvConfigFile, path)
+	t.Setenv("GRPC_REST_PORT", "6002")
+	t.Setenv("GRPC_ONLY_JSON", "false")
+
+	c := generate(FromEnv())
+	if err := c.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.gRPCPort != "6000" || c.restPort != "6002" {
+		t.Errorf("env should override the file, got ports %s %s", c.gRPCPort, c.restPort)
+	}
+	if c.enablePrometheus || c.onlyJSON || !c.enableCORS {
+		t.Errorf("unexpected flags prometheus=%v onlyJSON=%v cors=%v", c.enablePrometheus, c.onlyJSON, c.enableCORS)
+	}
+	if c.healthCheckInterval != 20*time.Second {
+		t.Errorf("unexpected health check interval %v", c.healthCheckInterval)
+	}
+	if c.gRPCTLS == nil || c.gRPCTLS.KeyFile != "/etc/tls/tls.key" || c.restTLS != nil {
+		t.Errorf("unexpected tls %+v %+v", c.gRPCTLS, c.restTLS)
+	}
+}
+
+func TestFromFileJSON(t *testing.T) {
+	path := writeConfigFile(t, "grpc.json", `{"single_port": "7000", "single_port_metrics": true}`)
+	c := generate(FromFile(path))
+	if err := c.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.singlePort != "7000" || !c.singlePortMetrics {
+		t.Errorf("unexpected single port %s %v", c.singlePort, c.singlePortMetrics)
+	}
+
+	path = writeConfigFile(t, "grpc.json", `{"grpc_prot": "7000"}`)
+	if err := generate(FromFile(path)).validate(); !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "grpc_prot") {
+		t.Errorf("unknown keys should be reported, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		args []ConfigFunc
+		want string
+	}{
+		{"invalid port", []ConfigFunc{GRPCPort("grpc")}, `grpc port "grpc" is not a port number`},
+		{"port collision", []ConfigFunc{RESTPort(DefaultGRPCPort)}, "rest port 5758 is used by the grpc server"},
+		{"prometheus collision", []ConfigFunc{PrometheusPort(DefaultRESTPort)}, "prometheus port 8080 is used by the rest server"},
+		{"single port metrics", []ConfigFunc{SinglePortMetrics(true)}, "single port metrics need a single port"},
+		{"admin", []ConfigFunc{Admin(&AdminConfig{Token: "secret"}), EnablePrometheus(false)}, "needs prometheus enabled"},
+		{"admin token", []ConfigFunc{Admin(&AdminConfig{})}, "admin endpoints need a token"},
+		{"cors credentials", []ConfigFunc{CORS(&CORSPolicy{AllowedOrigins: []string{CORSAllowAll}, AllowCredentials: true})}, "cors credentials can not be allowed for any origin"},
+		{"tls", []ConfigFunc{GRPCTLS(&TLSConfig{CertFile: "tls.crt"})}, "grpc tls needs both a cert and a key file"},
+		{"gzip", []ConfigFunc{Gzip(12)}, "gzip level 12 is not between -1 and 9"},
+		{"connection age", []ConfigFunc{MaxConnectionAge(0, time.Second)}, "max connection age grace needs a max connection age"},
+	}
+	for _, tt := range tests {
+		err := generate(tt.args...).validate()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	if err := generate(EnablePrometheus(false), PrometheusPort(DefaultRESTPort)).validate(); err != nil {
+		t.Errorf("disabled prometheus port should not collide, got %v", err)
+	}
+}
+
+func TestRegisterReflection(t *testing.T) {
+	for _, r := range []bool{true, false} {
+		s := NewService(RegisterReflection(r)).(*service)
+		s.Init()
+		_, ok := s.server.GetServiceInfo()["grpc.reflection.v1.ServerReflection"]
+		if ok != r {
+			t.Errorf("reflection registered %v with RegisterReflection(%v)", ok, r)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package gogrpctest
+
+import (
+	"google.golang.org/grpc"
+	gogrpc "pkg.tanyudii.me/go-pkg/go-grpc"
+)
+
+const (
+	DefaultBufSize = 1024 * 1024
+)
+
+type Config struct {
+	bufSize       int
+	serviceArgs   []gogrpc.ConfigFunc
+	clientArgs    []gogrpc.ClientConfigFunc
+	setups        []func(svc gogrpc.Service)
+	registrations []func(s *grpc.Server)
+}
+
+type ConfigFunc func(c *Config)
+
+func BufSize(n int) ConfigFunc {
+	if n <= 0 {
+		n = DefaultBufSize
+	}
+	return func(c *Config) {
+		c.bufSize = n
+	}
+}
+
+// ServiceConfig configures the go-grpc service under test. Ports are
+// ignored, nothing is served on the network.
+func ServiceConfig(args ...gogrpc.ConfigFunc) ConfigFunc {
+	return func(c *Config) {
+		c.serviceArgs = append(c.serviceArgs, args...)
+	}
+}
+
+// ClientConfig configures the client connection, which already uses
+// gogrpc.ClientDefaultInterceptors.
+func ClientConfig(args ...gogrpc.ClientConfigFunc) ConfigFunc {
+	return func(c *Config) {
+		c.clientArgs = append(c.clientArgs, args...)
+	}
+}
+
+// Setup runs fn before the service is initialized, to register
+// interceptors, REST handlers, health checkers or mounts.
+func Setup(fn func(svc gogrpc.Service)) ConfigFunc {
+	return func(c *Config) {
+		c.setups = append(c.setups, fn)
+	}
+}
+
+// Register runs fn once the gRPC server exists, to register the services
+// under test, e.g. pb.RegisterOrderServiceServer(s, impl).
+func Register(fn func(s *grpc.Server)) ConfigFunc {
+	return func(c *Config) {
+		c.registrations = append(c.registrations, fn)
+	}
+}
+
+func generate(args ...ConfigFunc) *Config {
+	c := &Config{
+		bufSize: DefaultBufSize,
+	}
+	for i := range args {
+		args[i](c)
+	}
+	return c
+}
@@ -0,0 +1,126 @@
+// Package gogrpctest runs a go-grpc service in process for end to end tests,
+// with gRPC on a bufconn listener and the REST gateway on an httptest.Server.
+package gogrpctest
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/http/httptest"
+	gogrpc "pkg.tanyudii.me/go-pkg/go-grpc"
+	"testing"
+	"time"
+)
+
+const (
+	bufconnTarget   = "bufconn"
+	connectTimeout  = 5 * time.Second
+	shutdownTimeout = 5 * time.Second
+)
+
+type Server struct {
+	Service gogrpc.Service
+	// Conn is a ready client connection to the gRPC server.
+	Conn *grpc.ClientConn
+	// HTTP serves the REST gateway, requests go to HTTP.URL.
+	HTTP *httptest.Server
+
+	lis    *bufconn.Listener
+	cancel context.CancelFunc
+	done   chan error
+}
+
+// NewServer starts a service with all default interceptors and stops it
+// when the test ends.
+func NewServer(t testing.TB, args ...ConfigFunc) *Server {
+	t.Helper()
+	s, err := Start(args...)
+	if err != nil {
+		t.Fatalf("gogrpctest: failed to start server %v", err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("gogrpctest: failed to close server %v", err)
+		}
+	})
+	return s
+}
+
+// Start is NewServer for callers without a testing.TB, e.g. TestMain. The
+// caller must Close the server.
+func Start(args ...ConfigFunc) (*Server, error) {
+	cfg := generate(args...)
+	lis := bufconn.Listen(cfg.bufSize)
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+
+	svc := gogrpc.NewService(append(cfg.serviceArgs, gogrpc.GatewayDialer(dialer))...)
+	if err := svc.Validate(); err != nil {
+		return nil, err
+	}
+	for _, fn := range cfg.setups {
+		fn(svc)
+	}
+	svc.Init()
+	for _, fn := range cfg.registrations {
+		fn(svc.GetServer())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		Service: svc,
+		lis:     lis,
+		cancel:  cancel,
+		done:    make(chan error, 1),
+	}
+	go func() {
+		s.done <- svc.ServeGRPC(ctx, lis)
+	}()
+
+	handler, err := svc.RESTHTTPHandler(ctx)
+	if err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	s.HTTP = httptest.NewServer(handler)
+
+	clientArgs := append([]gogrpc.ClientConfigFunc{
+		gogrpc.ClientDefaultInterceptors(),
+		gogrpc.ClientConnectTimeout(connectTimeout),
+		gogrpc.ClientDialOption(grpc.WithContextDialer(dialer)),
+	}, cfg.clientArgs...)
+	if s.Conn, err = gogrpc.NewClientConnWithCtx(ctx, bufconnTarget, clientArgs...); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close shuts the service down the way it is in production and releases
+// the connection, listener and HTTP server.
+func (s *Server) Close() error {
+	var errs []error
+	if s.Conn != nil {
+		if err := s.Conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.HTTP != nil {
+		s.HTTP.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.Service.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	s.cancel()
+	if err := <-s.done; err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		errs = append(errs, err)
+	}
+	_ = s.lis.Close()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+package gogrpctest
+
+import (
+	"compress/gzip"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"net/http"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gogrpc "pkg.tanyudii.me/go-pkg/go-grpc"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	s := NewServer(t)
+
+	resp, err := grpc_health_v1.NewHealthClient(s.Conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("unexpected status %v", resp.Status)
+	}
+
+	res, err := http.Get(s.HTTP.URL + gogrpc.HealthPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("gateway should reach the gRPC server, got %d", res.StatusCode)
+	}
+}
+
+func TestServerErrorMapping(t *testing.T) {
+	t.Parallel()
+	s := NewServer(t, Setup(func(svc gogrpc.Service) {
+		svc.RegisterUnaryServerInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return nil, goerr.NewNotFoundError("missing")
+		})
+	}))
+
+	_, err := grpc_health_v1.NewHealthClient(s.Conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if !goerr.IsNotFoundError(err) {
+		t.Errorf("client should get a typed error, got %v", err)
+	}
+
+	res, err := http.Get(s.HTTP.URL + gogrpc.HealthPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("gateway should map the error, got %d", res.StatusCode)
+	}
+}
+
+func TestServerOptions(t *testing.T) {
+	t.Parallel()
+	s := NewServer(t,
+		ServiceConfig(gogrpc.MaxRecvMsgSize(64), gogrpc.Gzip(gzip.BestSpeed), gogrpc.MaxConnectionAge(time.Minute, time.Second)),
+		ClientConfig(gogrpc.ClientGzip()),
+	)
+	client := grpc_health_v1.NewHealthClient(s.Conn)
+
+	if _, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatalf("gzip call should succeed, got %v", err)
+	}
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: strings.Repeat("x", 128)})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("message over the receive limit should be rejected, got %v", err)
+	}
+}
@@ -2,25 +2,205 @@ package go_grpc
 
 import (
 	"context"
-	"google.golang.org/grpc/codes"
+	"encoding/json"
+	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
-	"google.golang.org/grpc/status"
+	"net/http"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	"sync"
+	"time"
 )
 
-type healthServer struct{}
+const (
+	HealthPath          = "/_health"
+	HealthLivenessPath  = "/_health/live"
+	HealthReadinessPath = "/_health/ready"
+)
+
+// HealthCheckFunc probes a single dependency, returning nil when it is usable.
+type HealthCheckFunc func(ctx context.Context) error
+
+type Health interface {
+	RegisterChecker(name string, fn HealthCheckFunc)
+	SetServingStatus(service string, status grpc_health_v1.HealthCheckResponse_ServingStatus)
+	GetServingStatus(service string) grpc_health_v1.HealthCheckResponse_ServingStatus
+	Shutdown()
+	Resume()
+}
+
+// healthServer keeps the statuses in its own map, which the embedded
+// health.Server answering Check and Watch mirrors while not shut down.
+type healthServer struct {
+	*health.Server
+
+	mu       sync.RWMutex
+	checkers map[string]HealthCheckFunc
+	statuses map[string]grpc_health_v1.HealthCheckResponse_ServingStatus
+	shutdown bool
+	interval time.Duration
+	timeout  time.Duration
+}
+
+var (
+	_ grpc_health_v1.HealthServer = (*healthServer)(nil)
+	_ Health                      = (*healthServer)(nil)
+)
+
+func newHealthServer(interval, timeout time.Duration) *healthServer {
+	return &healthServer{
+		Server:   health.NewServer(),
+		checkers: make(map[string]HealthCheckFunc),
+		statuses: map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+			"": grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		interval: interval,
+		timeout:  timeout,
+	}
+}
+
+func (h *healthServer) RegisterChecker(name string, fn HealthCheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers[name] = fn
+}
+
+func (h *healthServer) SetServingStatus(service string, status grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.statuses[service] = status
+	if !h.shutdown {
+		h.Server.SetServingStatus(service, status)
+	}
+}
 
-var _ grpc_health_v1.HealthServer = (*healthServer)(nil)
+func (h *healthServer) GetServingStatus(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.statusLocked(service)
+}
 
-func newHealthServer() *healthServer {
-	return &healthServer{}
+func (h *healthServer) statusLocked(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if h.shutdown {
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+	if status, ok := h.statuses[service]; ok {
+		return status
+	}
+	return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
 }
 
-func (h *healthServer) Check(_ context.Context, _ *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
-	return &grpc_health_v1.HealthCheckResponse{
-		Status: grpc_health_v1.HealthCheckResponse_SERVING,
-	}, nil
+func (h *healthServer) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shutdown = true
+	h.Server.Shutdown()
+}
+
+func (h *healthServer) Resume() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shutdown = false
+	h.Server.Resume()
+	// Resume sets every service to SERVING, whatever the probes reported
+	for service, status := range h.statuses {
+		h.Server.SetServingStatus(service, status)
+	}
+}
+
+// run probes every registered checker until ctx is done. Each checker is
+// exposed as its own service name, and the overall ("") status is SERVING
+// only while every checker passes.
+func (h *healthServer) run(ctx context.Context) {
+	h.probe(ctx)
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.probe(ctx)
+		}
+	}
+}
+
+func (h *healthServer) probe(ctx context.Context) {
+	h.mu.RLock()
+	checkers := make(map[string]HealthCheckFunc, len(h.checkers))
+	for name, fn := range h.checkers {
+		checkers[name] = fn
+	}
+	h.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	results := make(map[string]error, len(checkers))
+	for name, fn := range checkers {
+		wg.Add(1)
+		go func(name string, fn HealthCheckFunc) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+			err := fn(checkCtx)
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}(name, fn)
+	}
+	wg.Wait()
+
+	overall := grpc_health_v1.HealthCheckResponse_SERVING
+	for name, err := range results {
+		status := grpc_health_v1.HealthCheckResponse_SERVING
+		if err != nil {
+			status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+			overall = status
+		}
+		prev := h.GetServingStatus(name)
+		h.SetServingStatus(name, status)
+		if prev == status {
+			continue
+		}
+		if err != nil {
+			gologger.WithError(err).Warnf("go grpc health check %s is not serving", name)
+		} else if prev != grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN {
+			gologger.Infof("go grpc health check %s is serving again", name)
+		}
+	}
+	h.SetServingStatus("", overall)
+}
+
+type healthResponse struct {
+	Status   string            `json:"status"`
+	Services map[string]string `json:"services,omitempty"`
+}
+
+func (h *healthServer) livenessHandler(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	writeHealthResponse(w, http.StatusOK, &healthResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING.String()})
+}
+
+func (h *healthServer) readinessHandler(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	overall := h.GetServingStatus("")
+	resp := &healthResponse{Status: overall.String(), Services: make(map[string]string)}
+	h.mu.RLock()
+	for name := range h.statuses {
+		if name != "" {
+			resp.Services[name] = h.statusLocked(name).String()
+		}
+	}
+	h.mu.RUnlock()
+
+	code := http.StatusOK
+	if overall != grpc_health_v1.HealthCheckResponse_SERVING {
+		code = http.StatusServiceUnavailable
+	}
+	writeHealthResponse(w, code, resp)
 }
 
-func (h *healthServer) Watch(_ *grpc_health_v1.HealthCheckRequest, _ grpc_health_v1.Health_WatchServer) error {
-	return status.Error(codes.Unimplemented, "unimplemented")
+func writeHealthResponse(w http.ResponseWriter, code int, resp *healthResponse) {
+	w.Header().Set(HeaderContentType, "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		gologger.Errorf("go grpc health: failed to write response %v", err)
+	}
 }
@@ -0,0 +1,62 @@
+package go_grpc
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"testing"
+	"time"
+)
+
+func TestHealthServerProbe(t *testing.T) {
+	h := newHealthServer(time.Second, time.Second)
+	h.RegisterChecker("mysql", func(ctx context.Context) error { return nil })
+	h.RegisterChecker("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+	h.probe(context.Background())
+
+	testCases := []struct {
+		service  string
+		expected grpc_health_v1.HealthCheckResponse_ServingStatus
+	}{
+		{service: "", expected: grpc_health_v1.HealthCheckResponse_NOT_SERVING},
+		{service: "mysql", expected: grpc_health_v1.HealthCheckResponse_SERVING},
+		{service: "redis", expected: grpc_health_v1.HealthCheckResponse_NOT_SERVING},
+	}
+	for _, tt := range testCases {
+		resp, err := h.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: tt.service})
+		if err != nil {
+			t.Errorf("service %q: unexpected error %v", tt.service, err)
+			continue
+		}
+		if resp.Status != tt.expected {
+			t.Errorf("service %q: status should be %v, got %v", tt.service, tt.expected, resp.Status)
+		}
+	}
+
+	if _, err := h.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "unknown"}); err == nil {
+		t.Errorf("unknown service should return error")
+	}
+
+	h.Shutdown()
+	if status := h.GetServingStatus("mysql"); status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("status after shutdown should be NOT_SERVING, got %v", status)
+	}
+}
+
+func TestHealthServerResume(t *testing.T) {
+	h := newHealthServer(time.Second, time.Second)
+	h.RegisterChecker("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+	h.Shutdown()
+	h.probe(context.Background())
+	h.Resume()
+
+	for _, service := range []string{"", "redis"} {
+		resp, err := h.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != grpc_health_v1.HealthCheckResponse_NOT_SERVING || h.GetServingStatus(service) != resp.Status {
+			t.Errorf("%q: check should report the probed status after resume, got %v and %v", service, resp.Status, h.GetServingStatus(service))
+		}
+	}
+}
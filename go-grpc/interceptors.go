@@ -5,46 +5,77 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
-	gologger "pkg.tanyudii.me/go-pkg/go-logger"
 	gotex "pkg.tanyudii.me/go-pkg/go-tex"
-	"runtime/debug"
 	"strings"
 	"time"
 )
 
-func RecoveryUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+func RecoveryUnaryServerInterceptor(hooks ...PanicHook) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		ctx = gotex.WithVerifiedIdentity(ctx)
 		defer func() {
 			if r := recover(); r != nil {
-				err = status.Errorf(codes.Unknown, "unexpected error happened")
-				gologger.WithField("stacktrace", string(debug.Stack())).Errorf("panic recovered: %v", r)
+				err = recoverPanic(ctx, panicInfoFromContext(ctx, info.FullMethod, r), hooks)
 			}
 		}()
 		return handler(ctx, req)
 	}
 }
 
+func RecoveryStreamServerInterceptor(hooks ...PanicHook) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx := gotex.WithVerifiedIdentity(ss.Context())
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(ctx, panicInfoFromContext(ctx, info.FullMethod, r), hooks)
+			}
+		}()
+		return handler(srv, WrapServerStream(ss, ctx))
+	}
+}
+
 func RequestIDUnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
-		md := gotex.FromIncoming(ctx)
-		if md.Get(strings.ToLower(gotex.RequestHeaderKeyRequestID)) == "" {
-			requestID := fmt.Sprintf("%s-%d", uuid.NewString(), time.Now().Unix())
-			md.Set(strings.ToLower(gotex.RequestHeaderKeyRequestID), requestID)
-			ctx = gotex.NewContext(ctx, gotex.NewGotex(md))
-		}
-		return handler(ctx, req)
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+func RequestIDStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, WrapServerStream(ss, withRequestID(ss.Context())))
 	}
 }
 
 func AcceptLangUnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
-		md := gotex.FromIncoming(ctx)
-		if acceptLang := md.Get(strings.ToLower("grpcgateway-" + gotex.RequestHeaderKeyAcceptLanguage)); acceptLang != "" {
-			md.Set(strings.ToLower(gotex.RequestHeaderKeyAcceptLanguage), acceptLang)
-			ctx = gotex.NewContext(ctx, gotex.NewGotex(md))
-		}
-		return handler(ctx, req)
+		return handler(withAcceptLang(ctx), req)
 	}
 }
+
+func AcceptLangStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, WrapServerStream(ss, withAcceptLang(ss.Context())))
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	md := gotex.FromIncoming(ctx)
+	if md.Get(strings.ToLower(gotex.RequestHeaderKeyRequestID)) == "" {
+		md.Set(strings.ToLower(gotex.RequestHeaderKeyRequestID), newRequestID())
+		ctx = md.ToIncoming(gotex.NewContext(ctx, gotex.NewGotex(md)))
+	}
+	return ctx
+}
+
+func withAcceptLang(ctx context.Context) context.Context {
+	md := gotex.FromIncoming(ctx)
+	if acceptLang := md.Get(strings.ToLower("grpcgateway-" + gotex.RequestHeaderKeyAcceptLanguage)); acceptLang != "" {
+		md.Set(strings.ToLower(gotex.RequestHeaderKeyAcceptLanguage), acceptLang)
+		ctx = md.ToIncoming(gotex.NewContext(ctx, gotex.NewGotex(md)))
+	}
+	return ctx
+}
+
+func newRequestID() string {
+	return fmt.Sprintf("%s-%d", uuid.NewString(), time.Now().Unix())
+}
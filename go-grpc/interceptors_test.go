@@ -0,0 +1,88 @@
+package go_grpc
+
+import (
+	"context"
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+)
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptorsContext(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"grpcgateway-accept-language", "id_ID",
+	))
+
+	interceptor := grpcmiddleware.ChainStreamServer(
+		RequestIDStreamServerInterceptor(),
+		RecoveryStreamServerInterceptor(),
+		AcceptLangStreamServerInterceptor(),
+	)
+
+	var got *gotex.Gotex
+	handler := func(_ interface{}, ss grpc.ServerStream) error {
+		got, _ = gotex.FromContext(ss.Context())
+		return nil
+	}
+
+	if err := interceptor(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, handler); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil {
+		t.Fatalf("gotex should be exist in stream context")
+	}
+	if got.RequestID == "" {
+		t.Errorf("request id should not be empty")
+	}
+	if got.AcceptLanguage != "id_ID" {
+		t.Errorf("accept language should be 'id_ID', got '%s'", got.AcceptLanguage)
+	}
+}
+
+func TestRecoveryStreamServerInterceptor(t *testing.T) {
+	interceptor := RecoveryStreamServerInterceptor()
+	err := interceptor(nil, &testServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{}, func(_ interface{}, _ grpc.ServerStream) error {
+		panic("boom")
+	})
+	if err == nil {
+		t.Errorf("error should not be nil")
+	}
+}
+
+func TestRecoveryUnaryServerInterceptor(t *testing.T) {
+	var hooked *PanicInfo
+	interceptor := RecoveryUnaryServerInterceptor(func(_ context.Context, info *PanicInfo) {
+		hooked = info
+	}, func(_ context.Context, _ *PanicInfo) {
+		panic("broken hook")
+	})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("requestid", "req-1", "userid", "spoofed", "companyid", "spoofed"))
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/order.Service/Create"}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		// as done by the auth interceptor
+		gotex.SetVerifiedIdentity(ctx, &gotex.Gotex{UserID: "user-1"})
+		panic("boom")
+	})
+
+	if !goerr.IsInternalServerError(err) || goerr.GetErrorName(err) != ErrNamePanic {
+		t.Fatalf("error should be a PANIC InternalServerError, got %v", err)
+	}
+	custom := goerr.FromStatus(status.Convert(err))
+	if custom.GetMetadata()[errMetaKeyRequestID] != "req-1" {
+		t.Errorf("error should carry the request id, got %v", custom.GetMetadata())
+	}
+	if hooked == nil || hooked.Value != "boom" || hooked.Method != "/order.Service/Create" || hooked.UserID != "user-1" || hooked.CompanyID != "" || len(hooked.Stack) == 0 {
+		t.Errorf("hook should get the panic info, got %+v", hooked)
+	}
+}
@@ -0,0 +1,279 @@
+package go_grpc
+
+import (
+	"bufio"
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"net"
+	"net/http"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	"strconv"
+	"time"
+)
+
+const (
+	metricsUnknownPath = "unknown"
+)
+
+var (
+	defaultDurationBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+	defaultSizeBuckets     = prometheus.ExponentialBuckets(64, 4, 10)
+
+	RpcDurationsHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_rpc_durations_histogram",
+		Help:    "GRPC RPC latency distributions.",
+		Buckets: defaultDurationBuckets,
+	}, []string{"httpCode", "grpcCode", "grpcMethod", "statusCode"})
+	RpcInFlightGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "grpc_rpc_in_flight",
+		Help: "GRPC RPCs currently being handled.",
+	}, []string{"grpcMethod"})
+	RpcRequestSizeHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_rpc_request_size_bytes",
+		Help:    "GRPC RPC request message size distributions.",
+		Buckets: defaultSizeBuckets,
+	}, []string{"grpcMethod"})
+	RpcResponseSizeHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_rpc_response_size_bytes",
+		Help:    "GRPC RPC response message size distributions.",
+		Buckets: defaultSizeBuckets,
+	}, []string{"grpcMethod"})
+
+	HttpDurationsHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_durations_histogram",
+		Help:    "HTTP request latency distributions.",
+		Buckets: defaultDurationBuckets,
+	}, []string{"httpCode", "httpMethod", "httpPath"})
+	HttpInFlightGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being handled.",
+	})
+	HttpRequestSizeHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_size_bytes",
+		Help:    "HTTP request body size distributions.",
+		Buckets: defaultSizeBuckets,
+	}, []string{"httpMethod", "httpPath"})
+	HttpResponseSizeHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "HTTP response body size distributions.",
+		Buckets: defaultSizeBuckets,
+	}, []string{"httpMethod", "httpPath"})
+)
+
+type metrics struct {
+	rpc         bool
+	inFlight    bool
+	messageSize bool
+	http        bool
+}
+
+func newMetrics(cfg *Config) *metrics {
+	return &metrics{
+		rpc:         cfg.enableRPCMetrics,
+		inFlight:    cfg.enableInFlightMetrics,
+		messageSize: cfg.enableMessageSizeMetrics,
+		http:        cfg.enableHTTPMetrics,
+	}
+}
+
+func (m *metrics) collectors() []prometheus.Collector {
+	var c []prometheus.Collector
+	if m.rpc {
+		c = append(c, RpcDurationsHistogram)
+	}
+	if m.inFlight {
+		c = append(c, RpcInFlightGauge)
+	}
+	if m.messageSize {
+		c = append(c, RpcRequestSizeHistogram, RpcResponseSizeHistogram)
+	}
+	if m.http {
+		c = append(c, HttpDurationsHistogram, HttpRequestSizeHistogram, HttpResponseSizeHistogram)
+		if m.inFlight {
+			c = append(c, HttpInFlightGauge)
+		}
+	}
+	return c
+}
+
+func (m *metrics) unaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		done := m.begin(info.FullMethod)
+		m.observeMessageSize(RpcRequestSizeHistogram, info.FullMethod, req)
+		resp, err = handler(ctx, req)
+		if err == nil {
+			m.observeMessageSize(RpcResponseSizeHistogram, info.FullMethod, resp)
+		}
+		done(err)
+		return resp, err
+	}
+}
+
+func (m *metrics) streamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		done := m.begin(info.FullMethod)
+		if m.messageSize {
+			ss = &metricsServerStream{ServerStream: ss, metrics: m, fullMethod: info.FullMethod}
+		}
+		err = handler(srv, ss)
+		done(err)
+		return err
+	}
+}
+
+func (m *metrics) begin(fullMethod string) func(err error) {
+	start := time.Now()
+	if m.inFlight {
+		RpcInFlightGauge.WithLabelValues(fullMethod).Inc()
+	}
+	return func(err error) {
+		if m.inFlight {
+			RpcInFlightGauge.WithLabelValues(fullMethod).Dec()
+		}
+		if !m.rpc {
+			return
+		}
+		code := status.Code(err)
+		RpcDurationsHistogram.WithLabelValues(
+			strconv.Itoa(goerr.HTTPStatusFromCode(code)),
+			code.String(),
+			fullMethod,
+			strconv.Itoa(goerr.GetErrorCode(err)),
+		).Observe(float64(time.Since(start).Milliseconds()))
+	}
+}
+
+func (m *metrics) observeMessageSize(h *prometheus.HistogramVec, fullMethod string, msg interface{}) {
+	if !m.messageSize {
+		return
+	}
+	if pm, ok := msg.(proto.Message); ok {
+		h.WithLabelValues(fullMethod).Observe(float64(proto.Size(pm)))
+	}
+}
+
+type metricsServerStream struct {
+	grpc.ServerStream
+	metrics    *metrics
+	fullMethod string
+}
+
+func (s *metricsServerStream) SendMsg(msg interface{}) error {
+	err := s.ServerStream.SendMsg(msg)
+	if err == nil {
+		s.metrics.observeMessageSize(RpcResponseSizeHistogram, s.fullMethod, msg)
+	}
+	return err
+}
+
+func (s *metricsServerStream) RecvMsg(msg interface{}) error {
+	err := s.ServerStream.RecvMsg(msg)
+	if err == nil {
+		s.metrics.observeMessageSize(RpcRequestSizeHistogram, s.fullMethod, msg)
+	}
+	return err
+}
+
+type httpRouteKey struct{}
+
+// httpRoute is filled by the gateway metadata annotator, which is the only
+// place the matched path pattern is visible outside the generated handler.
+type httpRoute struct {
+	pattern string
+}
+
+func httpRouteAnnotator(ctx context.Context, _ *http.Request) metadata.MD {
+	if route, ok := ctx.Value(httpRouteKey{}).(*httpRoute); ok {
+		if pattern, ok := runtime.HTTPPathPattern(ctx); ok {
+			route.pattern = pattern
+		}
+	}
+	return nil
+}
+
+// withHTTPRoute attaches a route holder to r, reusing the one an outer
+// middleware already attached.
+func withHTTPRoute(r *http.Request) (*http.Request, *httpRoute) {
+	if route, ok := r.Context().Value(httpRouteKey{}).(*httpRoute); ok {
+		return r, route
+	}
+	route := &httpRoute{pattern: metricsUnknownPath}
+	return r.WithContext(context.WithValue(r.Context(), httpRouteKey{}, route)), route
+}
+
+func (m *metrics) httpMiddleware(h http.Handler) http.Handler {
+	if !m.http {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		if m.inFlight {
+			HttpInFlightGauge.Inc()
+			defer HttpInFlightGauge.Dec()
+		}
+
+		r, route := withHTTPRoute(r)
+		rw := newResponseWriter(w)
+		h.ServeHTTP(rw, r)
+
+		HttpDurationsHistogram.WithLabelValues(
+			strconv.Itoa(rw.status),
+			r.Method,
+			route.pattern,
+		).Observe(float64(time.Since(start).Milliseconds()))
+		if r.ContentLength > 0 {
+			HttpRequestSizeHistogram.WithLabelValues(r.Method, route.pattern).Observe(float64(r.ContentLength))
+		}
+		HttpResponseSizeHistogram.WithLabelValues(r.Method, route.pattern).Observe(float64(rw.size))
+	})
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets mounted handlers, e.g. websockets, take over the connection.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,156 @@
+package go_grpc
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http"
+	"net/http/httptest"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func histogramCount(t *testing.T, h *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := h.WithLabelValues(labels...).(prometheus.Metric).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestMetricsCollectors(t *testing.T) {
+	if all := newMetrics(generate(EnableMessageSizeMetrics(true))).collectors(); len(all) != 8 {
+		t.Errorf("every collector should be enabled, got %d", len(all))
+	}
+	if c := newMetrics(generate()).collectors(); len(c) != 6 {
+		t.Errorf("message size collectors should be disabled by default, got %d", len(c))
+	}
+
+	m := newMetrics(generate(EnableRPCMetrics(false), EnableMessageSizeMetrics(false), EnableInFlightMetrics(false)))
+	c := m.collectors()
+	if len(c) != 3 || c[0] != HttpDurationsHistogram {
+		t.Errorf("only the http collectors should be left, got %d", len(c))
+	}
+	if got := newMetrics(generate(EnableHTTPMetrics(false), EnableInFlightMetrics(false), EnableMessageSizeMetrics(true))).collectors(); len(got) != 3 {
+		t.Errorf("only the rpc collectors should be left, got %d", len(got))
+	}
+}
+
+func TestMetricsUnaryServerInterceptor(t *testing.T) {
+	const method = "/metrics.Test/Unary"
+	interceptor := newMetrics(generate(EnableMessageSizeMetrics(true))).unaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+
+	var inFlight float64
+	_, err := interceptor(context.Background(), wrapperspb.String("hello"), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		inFlight = testutil.ToFloat64(RpcInFlightGauge.WithLabelValues(method))
+		return wrapperspb.String("world"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	badRequest := goerr.NewBadRequestError("invalid")
+	_, _ = interceptor(context.Background(), wrapperspb.String("hello"), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, badRequest
+	})
+
+	if inFlight != 1 || testutil.ToFloat64(RpcInFlightGauge.WithLabelValues(method)) != 0 {
+		t.Errorf("in flight should count the running call only, got %v", inFlight)
+	}
+	if n := histogramCount(t, RpcDurationsHistogram, "200", codes.OK.String(), method, "0"); n != 1 {
+		t.Errorf("successful call should be observed once, got %d", n)
+	}
+	if n := histogramCount(t, RpcDurationsHistogram, "400", codes.InvalidArgument.String(), method, strconv.Itoa(goerr.GetErrorCode(badRequest))); n != 1 {
+		t.Errorf("failed call should be observed by its codes, got %d", n)
+	}
+	if n := histogramCount(t, RpcRequestSizeHistogram, method); n != 2 {
+		t.Errorf("every request should be sized, got %d", n)
+	}
+	if n := histogramCount(t, RpcResponseSizeHistogram, method); n != 1 {
+		t.Errorf("only successful responses should be sized, got %d", n)
+	}
+
+	const disabled = "/metrics.Test/Disabled"
+	interceptor = newMetrics(generate(EnableRPCMetrics(false), EnableMessageSizeMetrics(false))).unaryServerInterceptor()
+	_, _ = interceptor(context.Background(), wrapperspb.String("hello"), &grpc.UnaryServerInfo{FullMethod: disabled}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return wrapperspb.String("world"), nil
+	})
+	if n := histogramCount(t, RpcDurationsHistogram, "200", codes.OK.String(), disabled, "0") + histogramCount(t, RpcRequestSizeHistogram, disabled); n != 0 {
+		t.Errorf("disabled metrics should not be observed, got %d", n)
+	}
+}
+
+type metricsTestStream struct {
+	grpc.ServerStream
+}
+
+func (metricsTestStream) Context() context.Context    { return context.Background() }
+func (metricsTestStream) SendMsg(_ interface{}) error { return nil }
+func (metricsTestStream) RecvMsg(_ interface{}) error { return nil }
+
+func TestMetricsStreamServerInterceptor(t *testing.T) {
+	const method = "/metrics.Test/Stream"
+	interceptor := newMetrics(generate(EnableMessageSizeMetrics(true))).streamServerInterceptor()
+
+	err := interceptor(nil, metricsTestStream{}, &grpc.StreamServerInfo{FullMethod: method}, func(srv interface{}, ss grpc.ServerStream) error {
+		_ = ss.RecvMsg(wrapperspb.String("hello"))
+		_ = ss.SendMsg(wrapperspb.String("a"))
+		return ss.SendMsg(wrapperspb.String("b"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := histogramCount(t, RpcDurationsHistogram, "200", codes.OK.String(), method, "0"); n != 1 {
+		t.Errorf("stream should be observed once, got %d", n)
+	}
+	if n := histogramCount(t, RpcRequestSizeHistogram, method); n != 1 {
+		t.Errorf("received messages should be sized, got %d", n)
+	}
+	if n := histogramCount(t, RpcResponseSizeHistogram, method); n != 2 {
+		t.Errorf("sent messages should be sized, got %d", n)
+	}
+}
+
+func TestMetricsHTTPMiddleware(t *testing.T) {
+	s := NewService().(*service)
+	s.Mount("/metrics-test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("mounted"))
+	}))
+	s.Init()
+	h, err := s.RESTHTTPHandler(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mounted := histogramCount(t, HttpDurationsHistogram, "200", http.MethodPost, "/metrics-test")
+	unknown := histogramCount(t, HttpDurationsHistogram, "404", http.MethodGet, metricsUnknownPath)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/metrics-test/hooks/1", strings.NewReader("payload")))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test-other/1", nil))
+
+	if n := histogramCount(t, HttpDurationsHistogram, "200", http.MethodPost, "/metrics-test"); n != mounted+1 {
+		t.Errorf("mounted routes should be labelled by their prefix, got %d", n-mounted)
+	}
+	if n := histogramCount(t, HttpDurationsHistogram, "404", http.MethodGet, metricsUnknownPath); n != unknown+1 {
+		t.Errorf("unmatched paths should be labelled %s, got %d", metricsUnknownPath, n-unknown)
+	}
+	if n := histogramCount(t, HttpRequestSizeHistogram, http.MethodPost, "/metrics-test"); n == 0 {
+		t.Error("request bodies should be sized")
+	}
+	if testutil.ToFloat64(HttpInFlightGauge) != 0 {
+		t.Error("in flight requests should be done")
+	}
+
+	before := testutil.CollectAndCount(HttpDurationsHistogram)
+	off := newMetrics(generate(EnableHTTPMetrics(false))).httpMiddleware(http.NotFoundHandler())
+	off.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/metrics-off", nil))
+	if testutil.CollectAndCount(HttpDurationsHistogram) != before {
+		t.Error("disabled http metrics should not be observed")
+	}
+}
@@ -0,0 +1,51 @@
+package go_grpc
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type mount struct {
+	prefix  string
+	handler http.Handler
+}
+
+// Mount serves h, e.g. a gin engine, for requests under the path prefix on
+// the REST server. Gateway routes take precedence, h only gets requests the
+// gateway has no route for, or no route with the request method. The path is passed on unchanged, wrap h with
+// http.StripPrefix when it expects paths relative to prefix.
+func (s *service) Mount(prefix string, h http.Handler) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	s.mounts = append(s.mounts, mount{prefix: prefix, handler: h})
+	// longest prefix first, so nested mounts win over their parents
+	sort.SliceStable(s.mounts, func(i, j int) bool {
+		return len(s.mounts[i].prefix) > len(s.mounts[j].prefix)
+	})
+}
+
+func (s *service) matchMount(path string) (mount, bool) {
+	for _, m := range s.mounts {
+		if m.prefix == "/" || path == m.prefix || strings.HasPrefix(path, m.prefix+"/") {
+			return m, true
+		}
+	}
+	return mount{}, false
+}
+
+// routingErrorHandler hands requests without a gateway route over to the
+// mounted handlers. They run behind the recovery of the REST handler.
+func (s *service) routingErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, httpStatus int) {
+	if httpStatus == http.StatusNotFound || httpStatus == http.StatusMethodNotAllowed {
+		if m, ok := s.matchMount(r.URL.Path); ok {
+			if route, ok := r.Context().Value(httpRouteKey{}).(*httpRoute); ok {
+				route.pattern = m.prefix
+			}
+			m.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+	MuxHandleRoutingError(ctx, mux, marshaler, w, r, httpStatus)
+}
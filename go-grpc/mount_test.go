@@ -0,0 +1,89 @@
+package go_grpc
+
+import (
+	"bufio"
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	s := NewService().(*service)
+	mux := runtime.NewServeMux(
+		runtime.WithRoutingErrorHandler(s.routingErrorHandler),
+		runtime.WithErrorHandler(MuxErrorHandler),
+	)
+	_ = mux.HandlePath(http.MethodGet, "/webhooks/gateway", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, _ = w.Write([]byte("gateway"))
+	})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/webhooks/payment", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetHeader(gotex.RequestHeaderKeyRequestID))
+	})
+	engine.GET("/webhooks/gateway", func(c *gin.Context) {
+		c.String(http.StatusOK, "mount")
+	})
+	engine.DELETE("/webhooks/gateway", func(c *gin.Context) {
+		c.String(http.StatusOK, "mount")
+	})
+	s.Mount("/webhooks/", engine)
+	s.Mount("/webhooks/panic", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	h := s.recoveryHTTPHandler(mux)
+
+	tests := []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{http.MethodGet, "/webhooks/gateway", http.StatusOK, "gateway"},
+		{http.MethodDelete, "/webhooks/gateway", http.StatusOK, "mount"},
+		{http.MethodPost, "/webhooks/payment", http.StatusOK, ""},
+		{http.MethodGet, "/webhooks/panic", http.StatusInternalServerError, ""},
+		{http.MethodGet, "/other", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, rec.Code)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s %s: expected body %q, got %q", tt.method, tt.path, tt.body, rec.Body)
+		}
+		if tt.path == "/webhooks/payment" && rec.Body.Len() == 0 {
+			t.Error("mounted handler should get a request id")
+		}
+		if tt.path == "/webhooks/panic" && !strings.Contains(rec.Body.String(), `"request_id"`) {
+			t.Errorf("recovered panic should report the request id, got %s", rec.Body)
+		}
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if _, _, err := newResponseWriter(rec).Hijack(); err != nil || !rec.hijacked {
+		t.Errorf("hijack should reach the underlying writer, got %v", err)
+	}
+	if _, _, err := newResponseWriter(httptest.NewRecorder()).Hijack(); err == nil {
+		t.Error("error should not be nil when the underlying writer can not hijack")
+	}
+}
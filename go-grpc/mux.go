@@ -9,9 +9,10 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"io"
+	"math"
 	"net/http"
 	goerr "pkg.tanyudii.me/go-pkg/go-err"
-	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	"strconv"
 	"strings"
 )
 
@@ -28,6 +29,10 @@ const (
 	HeaderKeyRequestID         = "requestid"
 	HeaderUserAgent            = "user-agent"
 	HeaderGRPCUserAgent        = "grpcgateway-user-agent"
+	HeaderRetryAfter           = "Retry-After"
+	HeaderIdempotencyKey       = "idempotency-key"
+
+	MIMEProtobuf = "application/x-protobuf"
 )
 
 var (
@@ -42,28 +47,13 @@ var (
 		HeaderKeyClientID:          HeaderKeyClientID,
 		HeaderKeyRequestID:         HeaderKeyRequestID,
 		HeaderUserAgent:            HeaderGRPCUserAgent,
+		HeaderIdempotencyKey:       HeaderIdempotencyKey,
 	}
 )
 
+// MuxCORS applies DefaultCORSPolicy in front of h.
 func MuxCORS(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if origin := r.Header.Get("Origin"); origin != "" {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
-				preflightHandler(w, r)
-				return
-			}
-		}
-		h.ServeHTTP(w, r)
-	})
-}
-
-func preflightHandler(w http.ResponseWriter, r *http.Request) {
-	headers := []string{HeaderContentType, HeaderAccept, HeaderAuthorization}
-	w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ","))
-	methods := []string{"GET", "HEAD", "POST", "PUT", "DELETE"}
-	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ","))
-	gologger.Infof("preflight request for %s\n", r.URL.Path)
+	return DefaultCORSPolicy().Handler(h)
 }
 
 func MuxHandleRoutingError(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, httpStatus int) {
@@ -79,6 +69,13 @@ func MuxHandleRoutingError(ctx context.Context, mux *runtime.ServeMux, marshaler
 }
 
 func MuxErrorHandler(_ context.Context, _ *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+	// HTTPStatusError overrides the status derived from the gRPC code
+	var httpStatus int
+	var statusErr *runtime.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		httpStatus = statusErr.HTTPStatus
+		err = statusErr.Err
+	}
 	s := status.Convert(err)
 
 	customStatus := goerr.FromStatus(s)
@@ -102,6 +99,10 @@ func MuxErrorHandler(_ context.Context, _ *runtime.ServeMux, m runtime.Marshaler
 		w.Header().Set("WWW-Authenticate", s.Message())
 	}
 
+	if retryAfter := goerr.GetRetryAfter(err); retryAfter > 0 {
+		w.Header().Set(HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+
 	buf, merr := m.Marshal(resp)
 	if merr != nil {
 		grpclog.Infof("Failed to marshal error message %q: %v", s, merr)
@@ -113,6 +114,9 @@ func MuxErrorHandler(_ context.Context, _ *runtime.ServeMux, m runtime.Marshaler
 	}
 
 	st := runtime.HTTPStatusFromCode(s.Code())
+	if httpStatus != 0 {
+		st = httpStatus
+	}
 	w.WriteHeader(st)
 	if _, err = w.Write(buf); err != nil {
 		grpclog.Infof("Failed to write response: %v", err)
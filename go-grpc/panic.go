@@ -0,0 +1,129 @@
+package go_grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	gotrace "pkg.tanyudii.me/go-pkg/go-trace"
+	"runtime/debug"
+	"strings"
+)
+
+const (
+	ErrNamePanic = "PANIC"
+
+	errMetaKeyRequestID = "request_id"
+)
+
+// PanicInfo describes a recovered panic of a gRPC call or REST request.
+type PanicInfo struct {
+	Value interface{}
+	Stack []byte
+	// Method is the full gRPC method, or "METHOD /path" for REST requests.
+	Method    string
+	RequestID string
+	// UserID, CompanyID and ClientID are of the identity verified by auth,
+	// empty when the panic happened before or without authentication.
+	UserID    string
+	CompanyID string
+	ClientID  string
+	TraceID   string
+}
+
+// PanicHook is called for every recovered panic, e.g. to alert or count
+// crashes. Panics of the hook itself are logged and ignored.
+type PanicHook func(ctx context.Context, info *PanicInfo)
+
+func panicInfoFromContext(ctx context.Context, method string, p interface{}) *PanicInfo {
+	md := gotex.FromIncoming(ctx)
+	return (&PanicInfo{
+		Value:     p,
+		Stack:     debug.Stack(),
+		Method:    method,
+		RequestID: md.Get(strings.ToLower(gotex.RequestHeaderKeyRequestID)),
+		TraceID:   gotrace.TraceID(ctx),
+	}).withIdentity(ctx)
+}
+
+func panicInfoFromRequest(r *http.Request, p interface{}) *PanicInfo {
+	return (&PanicInfo{
+		Value:     p,
+		Stack:     debug.Stack(),
+		Method:    r.Method + " " + r.URL.Path,
+		RequestID: r.Header.Get(gotex.RequestHeaderKeyRequestID),
+		TraceID:   gotrace.TraceID(r.Context()),
+	}).withIdentity(r.Context())
+}
+
+func (i *PanicInfo) withIdentity(ctx context.Context) *PanicInfo {
+	if gtx := gotex.VerifiedIdentity(ctx); gtx != nil {
+		i.UserID = gtx.UserID
+		i.CompanyID = gtx.CompanyID
+		i.ClientID = gtx.ClientID
+	}
+	return i
+}
+
+// recoverPanic logs the panic, calls the hooks and returns the error sent to
+// the client, which carries the request id to find the log entry.
+func recoverPanic(ctx context.Context, info *PanicInfo, hooks []PanicHook) error {
+	gologger.WithFields(logrus.Fields{
+		"panic":      fmt.Sprint(info.Value),
+		"stacktrace": string(info.Stack),
+		"method":     info.Method,
+		"request_id": info.RequestID,
+		"user_id":    info.UserID,
+		"company_id": info.CompanyID,
+		"client_id":  info.ClientID,
+		"trace_id":   info.TraceID,
+	}).Error("panic recovered")
+
+	for _, hook := range hooks {
+		callPanicHook(ctx, hook, info)
+	}
+
+	var metadata map[string]string
+	if info.RequestID != "" {
+		metadata = map[string]string{errMetaKeyRequestID: info.RequestID}
+	}
+	return goerr.NewInternalServerErrorWithNameAndMetadata("unexpected error happened", ErrNamePanic, metadata)
+}
+
+func callPanicHook(ctx context.Context, hook PanicHook, info *PanicInfo) {
+	defer func() {
+		if p := recover(); p != nil {
+			gologger.Errorf("go grpc: panic hook failed %v", p)
+		}
+	}()
+	hook(ctx, info)
+}
+
+// recoveryHTTPHandler gives REST handlers the request id and panic recovery
+// the gRPC interceptors give to gateway calls, e.g. for upload routes,
+// mounted handlers and the gateway itself.
+func (s *service) recoveryHTTPHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(gotex.RequestHeaderKeyRequestID) == "" {
+			r.Header.Set(gotex.RequestHeaderKeyRequestID, newRequestID())
+		}
+		r = r.WithContext(gotex.WithVerifiedIdentity(r.Context()))
+		rw := newResponseWriter(w)
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				err := recoverPanic(r.Context(), panicInfoFromRequest(r, p), s.cfg.panicHooks)
+				if !rw.wroteHeader {
+					MuxErrorHandler(r.Context(), nil, &runtime.JSONPb{}, rw, r, err)
+				}
+			}
+		}()
+		h.ServeHTTP(rw, r)
+	})
+}
@@ -12,7 +12,9 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/encoding/protojson"
 	"net"
@@ -21,53 +23,85 @@ import (
 	"os/signal"
 	gologger "pkg.tanyudii.me/go-pkg/go-logger"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
 var (
 	ErrServerNotInitialized = errors.New("[ERROR]: Server not initialized")
-	RpcDurationsHistogram   = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "grpc_rpc_durations_histogram",
-		Help:    "GRPC RPC latency distributions.",
-		Buckets: []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
-	}, []string{"httpCode", "grpcCode", "grpcMethod", "statusCode"})
 )
 
 type RESTHandler func(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) (err error)
 
 type Service interface {
 	Init()
+	Validate() error
 	Shutdown(ctx context.Context) error
 	GetServer() *grpc.Server
 	RunGracefully(t int)
 	RunServers(ctx context.Context) <-chan error
 	ListenAndServeGRPC(ctx context.Context) error
+	ServeGRPC(ctx context.Context, lis net.Listener) error
+	RESTHTTPHandler(ctx context.Context) (http.Handler, error)
 	ListenAndServeREST(ctx context.Context) error
 	ListenAndServePrometheus(ctx context.Context) error
+	ListenAndServeSinglePort(ctx context.Context) error
 	RegisterUnaryServerInterceptor(i ...grpc.UnaryServerInterceptor)
+	RegisterStreamServerInterceptor(i ...grpc.StreamServerInterceptor)
 	RegisterRESTHandler(handlers ...RESTHandler)
+	RegisterUploadHandler(pattern string, h UploadHandler, cfg ...UploadConfig)
+	Mount(prefix string, h http.Handler)
+	RegisterHealthChecker(name string, fn HealthCheckFunc)
+	RegisterShutdownHook(name string, fn ShutdownHook)
+	RegisterPrometheusCollector(c ...prometheus.Collector)
+	GetHealth() Health
 }
 
 type service struct {
 	cfg                  *Config
 	server               *grpc.Server
+	metrics              *metrics
+	accessLog            *accessLogger
+	health               *healthServer
 	interceptors         Interceptors
 	restHandlers         []RESTHandler
+	uploadRoutes         []uploadRoute
+	mounts               []mount
 	prometheusCollectors []prometheus.Collector
+
+	gRPCTLS       *certReloader
+	restTLS       *certReloader
+	prometheusTLS *certReloader
+
+	mu            sync.Mutex
+	httpServers   []*http.Server
+	gatewayConn   *grpc.ClientConn
+	shutdownHooks []shutdownHook
+	shutdownOnce  sync.Once
+	shutdownErr   error
+
+	// singlePortRequests counts the requests in flight on the single port
+	singlePortRequests atomic.Int64
 }
 
 type Interceptors struct {
-	serverUnary []grpc.UnaryServerInterceptor
+	serverUnary  []grpc.UnaryServerInterceptor
+	serverStream []grpc.StreamServerInterceptor
 }
 
 func NewService(args ...ConfigFunc) Service {
+	cfg := generate(args...)
 	return &service{
-		cfg: generate(args...),
+		cfg:       cfg,
+		metrics:   newMetrics(cfg),
+		accessLog: newAccessLogger(cfg.accessLog),
+		health:    newHealthServer(cfg.healthCheckInterval, cfg.healthCheckTimeout),
 	}
 }
 
 func (s *service) Init() {
+	s.initTLS()
 	s.initInterceptors()
 	s.initConfigRestServeMuxOpts()
 	s.initGRPCServer()
@@ -76,11 +110,6 @@ func (s *service) Init() {
 	s.registerHealthServer()
 }
 
-func (s *service) Shutdown(ctx context.Context) error {
-	<-ctx.Done()
-	return nil
-}
-
 func (s *service) RunGracefully(t int) {
 	mainCtx, cancelMainCtx := context.WithCancel(context.Background())
 	go func() {
@@ -95,7 +124,7 @@ func (s *service) RunGracefully(t int) {
 	gologger.Infof("go grpc is shutting down: for %ds %v", t, time.Now())
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(t)*time.Second)
 	defer cancel()
-	cancelMainCtx()
+	defer cancelMainCtx()
 	if err := s.Shutdown(ctx); err != nil {
 		gologger.Fatalf("go grpc shutdown err: %v", err)
 	}
@@ -112,59 +141,86 @@ func (s *service) RunServers(ctx context.Context) <-chan error {
 		})
 	}
 
-	go wg.Wrap(func() {
-		gologger.Infof("go grpc initializing gRPC connection in port %s", s.cfg.gRPCPort)
-		exitFunc(s.ListenAndServeGRPC(ctx))
-	})
+	if err := s.Validate(); err != nil {
+		gologger.Errorf("go grpc run servers: invalid config %v", err)
+		go exitFunc(err)
+		return exitCh
+	}
 
 	go wg.Wrap(func() {
-		gologger.Infof("go grpc initializing HTTP connection in port %s", s.cfg.restPort)
-		exitFunc(s.ListenAndServeREST(ctx))
+		s.health.run(ctx)
 	})
 
-	go wg.Wrap(func() {
-		gologger.Infof("go grpc initializing Prometheus connection in port %s", s.cfg.prometheusPort)
-		exitFunc(s.ListenAndServePrometheus(ctx))
-	})
+	if s.cfg.singlePort != "" {
+		go wg.Wrap(func() {
+			gologger.Infof("go grpc initializing single port connection in port %s", s.cfg.singlePort)
+			exitFunc(s.ListenAndServeSinglePort(ctx))
+		})
+	} else {
+		go wg.Wrap(func() {
+			gologger.Infof("go grpc initializing gRPC connection in port %s", s.cfg.gRPCPort)
+			exitFunc(s.ListenAndServeGRPC(ctx))
+		})
+
+		go wg.Wrap(func() {
+			gologger.Infof("go grpc initializing HTTP connection in port %s", s.cfg.restPort)
+			exitFunc(s.ListenAndServeREST(ctx))
+		})
+	}
+
+	if s.cfg.enablePrometheus && (s.cfg.singlePort == "" || !s.cfg.singlePortMetrics) {
+		go wg.Wrap(func() {
+			gologger.Infof("go grpc initializing Prometheus connection in port %s", s.cfg.prometheusPort)
+			exitFunc(s.ListenAndServePrometheus(ctx))
+		})
+	}
 
 	return exitCh
 }
 
-func (s *service) ListenAndServeGRPC(_ context.Context) error {
+func (s *service) ListenAndServeGRPC(ctx context.Context) error {
 	if s.server == nil {
 		return ErrServerNotInitialized
 	}
 	gologger.Infof("go grpc listen and serve grpc: %v", s.cfg.gRPCPort)
 
-	defer s.server.GracefulStop()
 	lis, err := net.Listen("tcp", ":"+s.cfg.gRPCPort)
 	if err != nil {
 		return err
 	}
 
+	return s.ServeGRPC(ctx, lis)
+}
+
+// ServeGRPC serves gRPC on lis until ctx is done, e.g. on a bufconn
+// listener in tests.
+func (s *service) ServeGRPC(ctx context.Context, lis net.Listener) error {
+	if s.server == nil {
+		return ErrServerNotInitialized
+	}
+	go s.shutdownOnDone(ctx, s.stopGRPC)
 	return s.server.Serve(lis)
 }
 
 func (s *service) ListenAndServeREST(ctx context.Context) error {
-	handler, err := s.initRESTHandler(ctx)
+	handler, err := s.restHTTPHandler(ctx)
 	if err != nil {
 		return err
 	}
 
 	srv := &http.Server{
 		Addr:    ":" + s.cfg.restPort,
-		Handler: MuxCORS(handler),
+		Handler: handler,
 	}
 
-	go func() {
-		<-ctx.Done()
-		if err = srv.Shutdown(context.Background()); err != nil {
-			gologger.Errorf("go grpc listen and serve rest: failed to shutdown %v", err)
-		}
-	}()
+	s.trackHTTPServer(srv)
+	go s.shutdownOnDone(ctx, func(ctx context.Context) {
+		_ = shutdownHTTPServer(ctx, srv)
+		s.closeGatewayConn()
+	})
 
 	gologger.Infof("go grpc listen and serve rest: %v", s.cfg.restPort)
-	if err = srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+	if err = listenAndServeHTTP(srv, s.restTLS); !errors.Is(err, http.ErrServerClosed) {
 		gologger.Errorf("go grpc listen and serve rest: failed to listen and serve %v", err)
 		return err
 	}
@@ -173,30 +229,22 @@ func (s *service) ListenAndServeREST(ctx context.Context) error {
 }
 
 func (s *service) ListenAndServePrometheus(ctx context.Context) (err error) {
-	for _, c := range s.prometheusCollectors {
-		if err = prometheus.Register(c); err != nil {
-			gologger.Errorf("go grpc listen and serve prometheus: failed to register %v", err)
-			return err
-		}
+	if err = s.registerPrometheusCollectors(); err != nil {
+		return err
 	}
 
-	mux := http.NewServeMux()
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", s.cfg.prometheusPort),
-		Handler: MuxCORS(mux),
+		Handler: s.corsHandler(s.prometheusHTTPHandler()),
 	}
 
-	mux.Handle("/metrics", promhttp.Handler())
-
-	go func() {
-		<-ctx.Done()
-		if err = srv.Shutdown(context.Background()); err != nil {
-			gologger.Errorf("go grpc listen and serve prometheus: failed to shutdown %v", err)
-		}
-	}()
+	s.trackHTTPServer(srv)
+	go s.shutdownOnDone(ctx, func(ctx context.Context) {
+		_ = shutdownHTTPServer(ctx, srv)
+	})
 
 	gologger.Infof("go grpc listen and serve prometheus: %v", s.cfg.prometheusPort)
-	if err = srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+	if err = listenAndServeHTTP(srv, s.prometheusTLS); !errors.Is(err, http.ErrServerClosed) {
 		gologger.Errorf("go grpc listen and serve prometheus: failed to listen and serve %v", err)
 		return err
 	}
@@ -204,6 +252,58 @@ func (s *service) ListenAndServePrometheus(ctx context.Context) (err error) {
 	return nil
 }
 
+// RESTHTTPHandler is the REST gateway with its middleware, as served by
+// ListenAndServeREST, e.g. to run it on an httptest.Server.
+func (s *service) RESTHTTPHandler(ctx context.Context) (http.Handler, error) {
+	return s.restHTTPHandler(ctx)
+}
+
+func (s *service) restHTTPHandler(ctx context.Context) (http.Handler, error) {
+	handler, err := s.initRESTHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	handler = s.metrics.httpMiddleware(s.corsHandler(s.recoveryHTTPHandler(handler)))
+	if s.accessLog != nil {
+		handler = s.accessLog.httpMiddleware(handler)
+	}
+	if s.cfg.enableTracing {
+		handler = tracingHTTPMiddleware(handler)
+	}
+	return handler, nil
+}
+
+func (s *service) registerPrometheusCollectors() error {
+	for _, c := range s.prometheusCollectors {
+		if err := prometheus.Register(c); err != nil {
+			// several services in one process, e.g. in tests, share the
+			// package level collectors
+			var are prometheus.AlreadyRegisteredError
+			if errors.As(err, &are) {
+				continue
+			}
+			gologger.Errorf("go grpc listen and serve prometheus: failed to register %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) prometheusHTTPHandler() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	s.registerAdminHandlers(mux)
+	return mux
+}
+
+func listenAndServeHTTP(srv *http.Server, r *certReloader) error {
+	if r == nil {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = r.serverConfig("h2", "http/1.1")
+	return srv.ListenAndServeTLS("", "")
+}
+
 func (s *service) GetServer() *grpc.Server {
 	return s.server
 }
@@ -212,27 +312,85 @@ func (s *service) RegisterUnaryServerInterceptor(interceptors ...grpc.UnaryServe
 	s.interceptors.serverUnary = append(s.interceptors.serverUnary, interceptors...)
 }
 
+func (s *service) RegisterStreamServerInterceptor(interceptors ...grpc.StreamServerInterceptor) {
+	s.interceptors.serverStream = append(s.interceptors.serverStream, interceptors...)
+}
+
 func (s *service) RegisterRESTHandler(handlers ...RESTHandler) {
 	s.restHandlers = append(s.restHandlers, handlers...)
 }
 
+// RegisterUploadHandler serves multipart/form-data POST requests to pattern,
+// e.g. "/v1/files/{folder}", on the REST gateway.
+func (s *service) RegisterUploadHandler(pattern string, h UploadHandler, cfg ...UploadConfig) {
+	r := uploadRoute{pattern: pattern, handler: h}
+	if len(cfg) > 0 {
+		r.cfg = cfg[0]
+	}
+	s.uploadRoutes = append(s.uploadRoutes, r)
+}
+
+func (s *service) RegisterHealthChecker(name string, fn HealthCheckFunc) {
+	s.health.RegisterChecker(name, fn)
+}
+
+// RegisterPrometheusCollector adds collectors, e.g. of go-cache, that are
+// registered along with the default ones once the servers start.
+func (s *service) RegisterPrometheusCollector(c ...prometheus.Collector) {
+	s.prometheusCollectors = append(s.prometheusCollectors, c...)
+}
+
+func (s *service) GetHealth() Health {
+	return s.health
+}
+
 func (s *service) initInterceptors() {
+	if s.cfg.enableTracing {
+		s.RegisterUnaryServerInterceptor(TracingUnaryServerInterceptor())
+		s.RegisterStreamServerInterceptor(TracingStreamServerInterceptor())
+	}
+	if s.cfg.gzipLevel != nil {
+		s.RegisterUnaryServerInterceptor(CompressionUnaryServerInterceptor(gzip.Name))
+		s.RegisterStreamServerInterceptor(CompressionStreamServerInterceptor(gzip.Name))
+	}
 	s.RegisterUnaryServerInterceptor(
+		s.metrics.unaryServerInterceptor(),
+		PeerIdentityUnaryServerInterceptor(),
 		RequestIDUnaryServerInterceptor(),
-		RecoveryUnaryServerInterceptor(),
+	)
+	s.RegisterStreamServerInterceptor(
+		s.metrics.streamServerInterceptor(),
+		PeerIdentityStreamServerInterceptor(),
+		RequestIDStreamServerInterceptor(),
+	)
+	if s.accessLog != nil {
+		s.RegisterUnaryServerInterceptor(s.accessLog.unaryServerInterceptor())
+		s.RegisterStreamServerInterceptor(s.accessLog.streamServerInterceptor())
+	}
+	s.RegisterUnaryServerInterceptor(
+		TimeoutUnaryServerInterceptor(s.cfg.defaultTimeout, s.cfg.methodTimeouts),
+		RecoveryUnaryServerInterceptor(s.cfg.panicHooks...),
 		AcceptLangUnaryServerInterceptor(),
 	)
+	s.RegisterStreamServerInterceptor(
+		TimeoutStreamServerInterceptor(s.cfg.defaultTimeout, s.cfg.methodTimeouts),
+		RecoveryStreamServerInterceptor(s.cfg.panicHooks...),
+		AcceptLangStreamServerInterceptor(),
+	)
 }
 
 func (s *service) initConfigRestServeMuxOpts() {
 	s.cfg.restServeMuxOpts = append(
 		s.cfg.restServeMuxOpts,
-		runtime.WithRoutingErrorHandler(MuxHandleRoutingError),
+		runtime.WithRoutingErrorHandler(s.routingErrorHandler),
 		runtime.WithErrorHandler(MuxErrorHandler),
 		runtime.WithIncomingHeaderMatcher(MuxIncomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(MuxOutgoingHeaderMatcher),
 		runtime.WithForwardResponseOption(MuxHandleRoutingRedirect),
-		runtime.WithHealthEndpointAt(grpc_health_v1.NewHealthClient(ClientConn(":"+s.cfg.gRPCPort)), "/_health"),
-		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+		runtime.WithMetadata(httpRouteAnnotator),
+		runtime.WithMetadata(s.traceAnnotator),
+		runtime.WithHealthEndpointAt(grpc_health_v1.NewHealthClient(s.gatewayClientConn()), HealthPath),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, newHTTPBodyMarshaler(&runtime.JSONPb{
 			UnmarshalOptions: protojson.UnmarshalOptions{
 				DiscardUnknown: s.cfg.discardUnknown,
 			},
@@ -241,32 +399,64 @@ func (s *service) initConfigRestServeMuxOpts() {
 				EmitUnpopulated:   true,
 				EmitDefaultValues: true,
 			},
-		}),
+		})),
 	)
+	// without OnlyJSON the gateway also speaks binary protobuf, picked by
+	// the Content-Type and Accept headers
+	if !s.cfg.onlyJSON {
+		s.cfg.restServeMuxOpts = append(
+			s.cfg.restServeMuxOpts,
+			runtime.WithMarshalerOption(MIMEProtobuf, &runtime.ProtoMarshaller{}),
+		)
+	}
+}
+
+func (s *service) traceAnnotator(ctx context.Context, r *http.Request) metadata.MD {
+	if !s.cfg.enableTracing {
+		return nil
+	}
+	return tracingAnnotator(ctx, r)
 }
 
 func (s *service) initGRPCServer() {
-	s.server = grpc.NewServer(grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(s.interceptors.serverUnary...)))
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(s.interceptors.serverUnary...)),
+		grpc.StreamInterceptor(grpcmiddleware.ChainStreamServer(s.interceptors.serverStream...)),
+	}
+	if s.gRPCTLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.gRPCTLS.serverConfig("h2"))))
+	}
+	if s.cfg.gzipLevel != nil {
+		// the level is global to the gzip compressor of the process
+		if err := gzip.SetLevel(*s.cfg.gzipLevel); err != nil {
+			gologger.Errorf("go grpc init server: failed to set gzip level %v", err)
+		}
+	}
+	s.server = grpc.NewServer(append(opts, s.cfg.serverOptions()...)...)
 }
 
 func (s *service) initReflection() {
+	if !s.cfg.registerReflection {
+		return
+	}
 	reflection.Register(s.GetServer())
 }
 
 func (s *service) initDefaultPrometheusCollectors() {
-	s.prometheusCollectors = append(s.prometheusCollectors, RpcDurationsHistogram)
+	s.prometheusCollectors = append(s.prometheusCollectors, s.metrics.collectors()...)
 }
 
 func (s *service) initRESTHandler(ctx context.Context) (http.Handler, error) {
 	mux := runtime.NewServeMux(s.cfg.restServeMuxOpts...)
-
-	creds := insecure.NewCredentials()
-	if s.cfg.tls {
-		creds = credentials.NewTLS(&tls.Config{})
+	if err := mux.HandlePath(http.MethodGet, HealthLivenessPath, s.health.livenessHandler); err != nil {
+		return nil, err
+	}
+	if err := mux.HandlePath(http.MethodGet, HealthReadinessPath, s.health.readinessHandler); err != nil {
+		return nil, err
 	}
 
-	endpoint := ":" + s.cfg.gRPCPort
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	endpoint := s.gatewayEndpoint()
+	opts := s.gatewayDialOpts()
 	for i := range s.restHandlers {
 		h := s.restHandlers[i]
 		if err := h(ctx, mux, endpoint, opts); err != nil {
@@ -274,9 +464,67 @@ func (s *service) initRESTHandler(ctx context.Context) (http.Handler, error) {
 		}
 	}
 
+	if len(s.uploadRoutes) > 0 {
+		conn := s.gatewayClientConn()
+		for _, r := range s.uploadRoutes {
+			if err := mux.HandlePath(http.MethodPost, r.pattern, r.handle(mux, conn)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return mux, nil
 }
 
+// gatewayEndpoint is the address the REST gateway dials to reach the gRPC
+// server of this service. In single port mode that is the shared listener.
+func (s *service) gatewayEndpoint() string {
+	if s.cfg.singlePort != "" {
+		return ":" + s.cfg.singlePort
+	}
+	return ":" + s.cfg.gRPCPort
+}
+
+// gatewayDialOpts are the options the REST gateway uses to dial the gRPC
+// listener of this service.
+func (s *service) gatewayDialOpts() []grpc.DialOption {
+	creds := insecure.NewCredentials()
+	if r := s.gRPCListenerTLS(); r != nil {
+		creds = credentials.NewTLS(r.gatewayConfig())
+	} else if s.cfg.tls {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(gatewayMarkerUnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(gatewayMarkerStreamClientInterceptor),
+		// the gateway receives what the server sends and the other way round
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(s.cfg.maxSendMsgSize),
+			grpc.MaxCallSendMsgSize(s.cfg.maxRecvMsgSize),
+		),
+	}
+	if s.cfg.gatewayDialer != nil {
+		opts = append(opts, grpc.WithContextDialer(s.cfg.gatewayDialer))
+	}
+	return opts
+}
+
+// gatewayClientConn is the connection of the REST gateway to the gRPC server,
+// shared by every gateway handler and closed on shutdown.
+func (s *service) gatewayClientConn() grpc.ClientConnInterface {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gatewayConn == nil {
+		conn, err := grpc.Dial(s.gatewayEndpoint(), s.gatewayDialOpts()...)
+		if err != nil {
+			panic(err)
+		}
+		s.gatewayConn = conn
+	}
+	return s.gatewayConn
+}
+
 func (s *service) registerHealthServer() {
-	grpc_health_v1.RegisterHealthServer(s.GetServer(), newHealthServer())
+	grpc_health_v1.RegisterHealthServer(s.GetServer(), s.health)
 }
@@ -0,0 +1,169 @@
+package go_grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	"time"
+)
+
+// ShutdownHook releases an application resource once the servers have
+// drained, e.g. closing a DB or redis client or flushing a queue.
+type ShutdownHook func(ctx context.Context) error
+
+type shutdownHook struct {
+	name string
+	fn   ShutdownHook
+}
+
+func (s *service) RegisterShutdownHook(name string, fn ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, shutdownHook{name: name, fn: fn})
+}
+
+// Shutdown drains the service: health is flipped to NOT_SERVING, the drain
+// period is awaited so load balancers stop routing, gRPC is stopped
+// gracefully (forced at the deadline), HTTP servers are shut down, the
+// gateway connection is closed and the shutdown hooks run in registration
+// order. In single port mode gRPC is stopped after the HTTP server, once the
+// requests in flight on the shared listener finished.
+func (s *service) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+	})
+	return s.shutdownErr
+}
+
+func (s *service) shutdown(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.shutdownTimeout)
+		defer cancel()
+	}
+
+	s.health.Shutdown()
+	if s.cfg.shutdownDrainPeriod > 0 {
+		gologger.Infof("go grpc shutdown: draining for %v", s.cfg.shutdownDrainPeriod)
+		select {
+		case <-time.After(s.cfg.shutdownDrainPeriod):
+		case <-ctx.Done():
+		}
+	}
+
+	if s.cfg.singlePort == "" {
+		s.stopGRPC(ctx)
+	}
+
+	var errs []error
+	for _, srv := range s.getHTTPServers() {
+		if err := shutdownHTTPServer(ctx, srv); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.cfg.singlePort != "" {
+		s.stopGRPC(ctx)
+	}
+	s.closeGatewayConn()
+
+	s.mu.Lock()
+	hooks := s.shutdownHooks
+	s.mu.Unlock()
+	for _, hook := range hooks {
+		if err := hook.fn(ctx); err != nil {
+			gologger.Errorf("go grpc shutdown: hook %s failed %v", hook.name, err)
+			errs = append(errs, fmt.Errorf("shutdown hook %s: %w", hook.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// stopGRPC waits for in-flight RPCs to finish and falls back to a hard stop
+// once ctx is done, so a slow stream can no longer hang the shutdown.
+func (s *service) stopGRPC(ctx context.Context) {
+	if s.server == nil {
+		return
+	}
+	// GracefulStop panics on the transports of ServeHTTP, which serves the
+	// single port, so the requests in flight there are awaited instead.
+	if s.cfg.singlePort != "" {
+		if !s.waitSinglePortRequests(ctx) {
+			gologger.Warnf("go grpc shutdown: graceful stop deadline exceeded, forcing stop")
+		}
+		s.server.Stop()
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		gologger.Warnf("go grpc shutdown: graceful stop deadline exceeded, forcing stop")
+		s.server.Stop()
+		<-done
+	}
+}
+
+// waitSinglePortRequests waits for the requests of the single port to
+// finish, reporting false when ctx is done first.
+func (s *service) waitSinglePortRequests(ctx context.Context) bool {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for s.singlePortRequests.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+func (s *service) closeGatewayConn() {
+	s.mu.Lock()
+	conn := s.gatewayConn
+	s.gatewayConn = nil
+	s.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	if err := conn.Close(); err != nil {
+		gologger.Errorf("go grpc shutdown: failed to close gateway conn %v", err)
+	}
+}
+
+func (s *service) trackHTTPServer(srv *http.Server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.httpServers = append(s.httpServers, srv)
+}
+
+func (s *service) getHTTPServers() []*http.Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.httpServers
+}
+
+func shutdownHTTPServer(ctx context.Context, srv *http.Server) error {
+	if err := srv.Shutdown(ctx); err != nil {
+		gologger.Errorf("go grpc shutdown: failed to shutdown http server %s %v", srv.Addr, err)
+		_ = srv.Close()
+		return err
+	}
+	return nil
+}
+
+// shutdownOnDone stops a single server once the serving context is canceled,
+// bounded by the configured shutdown timeout.
+func (s *service) shutdownOnDone(ctx context.Context, stop func(ctx context.Context)) {
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.shutdownTimeout)
+	defer cancel()
+	stop(shutdownCtx)
+}
@@ -0,0 +1,55 @@
+package go_grpc
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"testing"
+	"time"
+)
+
+func TestServiceShutdownHooks(t *testing.T) {
+	svc := NewService()
+
+	var order []string
+	svc.RegisterShutdownHook("db", func(ctx context.Context) error {
+		order = append(order, "db")
+		return nil
+	})
+	svc.RegisterShutdownHook("redis", func(ctx context.Context) error {
+		order = append(order, "redis")
+		return errors.New("already closed")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := svc.Shutdown(ctx); err == nil {
+		t.Errorf("error from hook should be returned")
+	}
+	if len(order) != 2 || order[0] != "db" || order[1] != "redis" {
+		t.Errorf("hooks should run in registration order, got %v", order)
+	}
+	if status := svc.GetHealth().GetServingStatus(""); status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("health should be NOT_SERVING after shutdown, got %v", status)
+	}
+}
+
+func TestServiceShutdownClosesGatewayConn(t *testing.T) {
+	s := NewService().(*service)
+	conn := s.gatewayClientConn()
+	if s.gatewayClientConn() != conn {
+		t.Error("gateway handlers should share one connection")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if state := conn.(*grpc.ClientConn).GetState(); state != connectivity.Shutdown {
+		t.Errorf("gateway connection should be closed, got %v", state)
+	}
+}
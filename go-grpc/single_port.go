@@ -0,0 +1,100 @@
+package go_grpc
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net/http"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	"strings"
+)
+
+const (
+	contentTypeGRPC = "application/grpc"
+)
+
+// ListenAndServeSinglePort serves gRPC and the REST gateway on one listener.
+// HTTP/2 requests with a gRPC content type go to the gRPC server, anything
+// else to the gateway. Without TLS, HTTP/2 is served in cleartext (h2c).
+func (s *service) ListenAndServeSinglePort(ctx context.Context) error {
+	if s.server == nil {
+		return ErrServerNotInitialized
+	}
+
+	handler, err := s.singlePortHandler(ctx)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:    ":" + s.cfg.singlePort,
+		Handler: handler,
+	}
+
+	s.trackHTTPServer(srv)
+	go s.shutdownOnDone(ctx, func(ctx context.Context) {
+		_ = shutdownHTTPServer(ctx, srv)
+		s.stopGRPC(ctx)
+		s.closeGatewayConn()
+	})
+
+	gologger.Infof("go grpc listen and serve single port: %v", s.cfg.singlePort)
+	if err = listenAndServeHTTP(srv, s.gRPCListenerTLS()); !errors.Is(err, http.ErrServerClosed) {
+		gologger.Errorf("go grpc listen and serve single port: failed to listen and serve %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// singlePortHandler routes gRPC, metrics and REST requests of the single
+// port listener.
+func (s *service) singlePortHandler(ctx context.Context) (http.Handler, error) {
+	restHandler, err := s.restHTTPHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var metricsHandler http.Handler
+	if s.cfg.singlePortMetrics && s.cfg.enablePrometheus {
+		if err = s.registerPrometheusCollectors(); err != nil {
+			return nil, err
+		}
+		metricsHandler = s.corsHandler(s.prometheusHTTPHandler())
+	}
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// h2c hijacks its connections, so the HTTP server shutdown does not
+		// wait for their requests, stopGRPC does
+		s.singlePortRequests.Add(1)
+		defer s.singlePortRequests.Add(-1)
+		switch {
+		case isGRPCRequest(r):
+			s.server.ServeHTTP(w, r)
+		case metricsHandler != nil && (r.URL.Path == "/metrics" || s.isAdminPath(r.URL.Path)):
+			metricsHandler.ServeHTTP(w, r)
+		default:
+			restHandler.ServeHTTP(w, r)
+		}
+	})
+
+	if s.gRPCListenerTLS() == nil {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	return handler, nil
+}
+
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get(HeaderContentType), contentTypeGRPC)
+}
+
+// gRPCListenerTLS is the certificate used by the listener serving gRPC.
+// In single port mode the REST certificate is used when no gRPC one is set.
+func (s *service) gRPCListenerTLS() *certReloader {
+	if s.cfg.singlePort != "" && s.gRPCTLS == nil {
+		return s.restTLS
+	}
+	return s.gRPCTLS
+}
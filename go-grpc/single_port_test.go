@@ -0,0 +1,107 @@
+package go_grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSinglePortShutdownWithOpenStream(t *testing.T) {
+	s := NewService(SinglePort("0")).(*service)
+	s.Init()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler, err := s.singlePortHandler(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(handler)
+	s.trackHTTPServer(ts.Config)
+	ts.Start()
+	defer ts.Close()
+
+	conn, err := grpc.Dial(ts.Listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	stream, err := grpc_health_v1.NewHealthClient(conn).Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer shutdownCancel()
+	if err = s.Shutdown(shutdownCtx); err != nil {
+		t.Errorf("shutdown should stop the single port, got %v", err)
+	}
+
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+	}
+}
+
+func TestSinglePortShutdownWithInFlightCall(t *testing.T) {
+	s := NewService(SinglePort("0")).(*service)
+	s.Init()
+	started := make(chan struct{})
+	s.GetServer().RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Slow",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Call",
+			Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				if err := dec(&emptypb.Empty{}); err != nil {
+					return nil, err
+				}
+				close(started)
+				time.Sleep(300 * time.Millisecond)
+				return &emptypb.Empty{}, nil
+			},
+		}},
+	}, struct{}{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler, err := s.singlePortHandler(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(handler)
+	s.trackHTTPServer(ts.Config)
+	ts.Start()
+	defer ts.Close()
+
+	conn, err := grpc.Dial(ts.Listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	called := make(chan error, 1)
+	go func() {
+		called <- conn.Invoke(ctx, "/test.Slow/Call", &emptypb.Empty{}, &emptypb.Empty{})
+	}()
+	<-started
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err = s.Shutdown(shutdownCtx); err != nil {
+		t.Errorf("shutdown should drain the single port, got %v", err)
+	}
+	if err = <-called; err != nil {
+		t.Errorf("in-flight call should complete, got %v", err)
+	}
+}
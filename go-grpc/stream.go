@@ -0,0 +1,23 @@
+package go_grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+)
+
+// ServerStream wraps grpc.ServerStream so interceptors can replace the
+// stream context, e.g. after rebuilding the gotex from incoming metadata.
+type ServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+var _ grpc.ServerStream = (*ServerStream)(nil)
+
+func WrapServerStream(ss grpc.ServerStream, ctx context.Context) *ServerStream {
+	return &ServerStream{ServerStream: ss, ctx: ctx}
+}
+
+func (s *ServerStream) Context() context.Context {
+	return s.ctx
+}
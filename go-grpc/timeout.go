@@ -0,0 +1,65 @@
+package go_grpc
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	"time"
+)
+
+const (
+	ErrNameDeadlineExceeded = "DEADLINE_EXCEEDED"
+)
+
+type MapMethodTimeouts map[string]time.Duration
+
+// TimeoutUnaryServerInterceptor bounds every call by the timeout of its
+// full method, or by defaultTimeout when it has none. A shorter deadline
+// sent by the client still wins. Zero means no server-side timeout.
+func TimeoutUnaryServerInterceptor(defaultTimeout time.Duration, methodTimeouts MapMethodTimeouts) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, cancel := withMethodTimeout(ctx, info.FullMethod, defaultTimeout, methodTimeouts)
+		defer cancel()
+		resp, err = handler(ctx, req)
+		return resp, deadlineExceededError(ctx, err)
+	}
+}
+
+func TimeoutStreamServerInterceptor(defaultTimeout time.Duration, methodTimeouts MapMethodTimeouts) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := withMethodTimeout(ss.Context(), info.FullMethod, defaultTimeout, methodTimeouts)
+		defer cancel()
+		return deadlineExceededError(ctx, handler(srv, WrapServerStream(ss, ctx)))
+	}
+}
+
+func withMethodTimeout(ctx context.Context, fullMethod string, defaultTimeout time.Duration, methodTimeouts MapMethodTimeouts) (context.Context, context.CancelFunc) {
+	timeout, ok := methodTimeouts[fullMethod]
+	if !ok {
+		timeout = defaultTimeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// deadlineExceededError maps the error of a call that ran out of time,
+// whether it surfaced as a context error or a status, to a goerr
+// DeadlineExceededError.
+func deadlineExceededError(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	var deadlineErr *goerr.DeadlineExceededError
+	if errors.As(err, &deadlineErr) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded {
+		return goerr.NewDeadlineExceededErrorWithName("deadline exceeded", ErrNameDeadlineExceeded)
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package go_grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+	"time"
+)
+
+func TestTimeoutUnaryServerInterceptor(t *testing.T) {
+	interceptor := TimeoutUnaryServerInterceptor(time.Second, MapMethodTimeouts{
+		"/order.Service/Slow": 10 * time.Millisecond,
+		"/order.Service/Free": 0,
+	})
+
+	var budget time.Duration
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/order.Service/Slow"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		budget, _ = gotex.GetRemainingBudget(ctx)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if budget <= 0 || budget > 10*time.Millisecond {
+		t.Errorf("handler budget should be within (0, 10ms], got %v", budget)
+	}
+	if !goerr.IsDeadlineExceededError(err) || status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("error should be DeadlineExceededError, got %v", err)
+	}
+
+	var hasDeadline bool
+	_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/order.Service/Free"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, hasDeadline = gotex.GetRemainingBudget(ctx)
+		return nil, nil
+	})
+	if hasDeadline {
+		t.Errorf("method with zero timeout should have no deadline")
+	}
+
+	clientCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _ = interceptor(clientCtx, nil, &grpc.UnaryServerInfo{FullMethod: "/order.Service/Get"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		budget, _ = gotex.GetRemainingBudget(ctx)
+		return nil, nil
+	})
+	if budget > 50*time.Millisecond {
+		t.Errorf("shorter client deadline should win, got %v", budget)
+	}
+}
@@ -0,0 +1,323 @@
+package go_grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"math/big"
+	"os"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTLSReloadInterval = time.Minute
+)
+
+var (
+	ErrTLSInvalidClientCA = errors.New("[ERROR]: TLS client CA contains no certificate")
+	ErrTLSPeerMismatch    = errors.New("[ERROR]: TLS peer certificate does not match the server certificate")
+)
+
+// TLSConfig describes the certificate files served by a listener. When
+// ClientCAFile is set, client certificates signed by it are verified, and
+// RequireClientCert turns that into mutual TLS.
+type TLSConfig struct {
+	CertFile          string `envconfig:"CERT_FILE" yaml:"cert_file" json:"cert_file"`
+	KeyFile           string `envconfig:"KEY_FILE" yaml:"key_file" json:"key_file"`
+	ClientCAFile      string `envconfig:"CLIENT_CA_FILE" yaml:"client_ca_file" json:"client_ca_file,omitempty"`
+	RequireClientCert bool   `envconfig:"REQUIRE_CLIENT_CERT" yaml:"require_client_cert" json:"require_client_cert"`
+}
+
+// certReloader serves the certificate files of a TLSConfig and reloads them
+// when their modification time changes, checked at most once per interval.
+type certReloader struct {
+	cfg      *TLSConfig
+	interval time.Duration
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(cfg *TLSConfig, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{cfg: cfg, interval: interval}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTimes[f] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%w: %s", ErrTLSInvalidClientCA, r.cfg.ClientCAFile)
+		}
+		gatewayCert, err := gatewayCertificate()
+		if err != nil {
+			return err
+		}
+		clientCAs.AddCert(gatewayCert.Leaf)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.checkedAt = time.Now()
+	return nil
+}
+
+func (r *certReloader) maybeReload() {
+	r.mu.RLock()
+	due := time.Since(r.checkedAt) >= r.interval
+	r.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	r.mu.Lock()
+	r.checkedAt = time.Now()
+	modTimes := r.modTimes
+	r.mu.Unlock()
+
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			gologger.Errorf("go grpc tls: failed to stat %s %v", f, err)
+			return
+		}
+		if !info.ModTime().Equal(modTimes[f]) {
+			if err = r.load(); err != nil {
+				gologger.Errorf("go grpc tls: failed to reload certificate, keep serving the previous one %v", err)
+				return
+			}
+			gologger.Infof("go grpc tls: certificate %s reloaded", r.cfg.CertFile)
+			return
+		}
+	}
+}
+
+func (r *certReloader) getCertificate() *tls.Certificate {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+func (r *certReloader) clientAuth() tls.ClientAuthType {
+	switch {
+	case r.cfg.RequireClientCert:
+		return tls.RequireAndVerifyClientCert
+	case r.cfg.ClientCAFile != "":
+		return tls.VerifyClientCertIfGiven
+	default:
+		return tls.NoClientCert
+	}
+}
+
+// serverConfig returns a tls.Config that resolves the certificate and client
+// CA pool per handshake, so reloaded files apply to new connections.
+func (r *certReloader) serverConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.getCertificate(), nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert := r.getCertificate()
+			r.mu.RLock()
+			clientCAs := r.clientCAs
+			r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   nextProtos,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    clientCAs,
+				ClientAuth:   r.clientAuth(),
+			}, nil
+		},
+	}
+}
+
+// gatewayConfig is used by the REST gateway to dial the gRPC listener of the
+// same process. The server certificate is pinned to the one being served
+// instead of verified against a CA, since the gateway dials a bare port.
+// Under mTLS the gateway presents its own certificate, see
+// gatewayCertificate.
+func (r *certReloader) gatewayConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			cert := r.getCertificate()
+			if len(rawCerts) == 0 || !bytes.Equal(rawCerts[0], cert.Certificate[0]) {
+				return ErrTLSPeerMismatch
+			}
+			return nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return gatewayCertificate()
+		},
+	}
+}
+
+var gatewayIdentity struct {
+	once sync.Once
+	cert *tls.Certificate
+	err  error
+}
+
+// gatewayCertificate is the client certificate of the REST gateway, made
+// once per process and trusted by every listener verifying client
+// certificates. It never counts as a peer identity, so calls through the
+// gateway are authenticated like the external REST request they come from.
+func gatewayCertificate() (*tls.Certificate, error) {
+	gatewayIdentity.once.Do(func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			gatewayIdentity.err = err
+			return
+		}
+		serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+		if err != nil {
+			gatewayIdentity.err = err
+			return
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: serial,
+			Subject:      pkix.Name{CommonName: "go-grpc-gateway"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().AddDate(10, 0, 0),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+		if err != nil {
+			gatewayIdentity.err = err
+			return
+		}
+		leaf, err := x509.ParseCertificate(der)
+		if err != nil {
+			gatewayIdentity.err = err
+			return
+		}
+		gatewayIdentity.cert = &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+	})
+	return gatewayIdentity.cert, gatewayIdentity.err
+}
+
+func isGatewayCertificate(cert *x509.Certificate) bool {
+	gatewayCert, err := gatewayCertificate()
+	return err == nil && bytes.Equal(cert.Raw, gatewayCert.Leaf.Raw)
+}
+
+func (s *service) initTLS() {
+	var err error
+	if s.cfg.gRPCTLS != nil {
+		if s.gRPCTLS, err = newCertReloader(s.cfg.gRPCTLS, s.cfg.tlsReloadInterval); err != nil {
+			gologger.Fatalf("go grpc tls: failed to load grpc certificate: %v", err)
+		}
+	}
+	if s.cfg.restTLS != nil {
+		if s.restTLS, err = newCertReloader(s.cfg.restTLS, s.cfg.tlsReloadInterval); err != nil {
+			gologger.Fatalf("go grpc tls: failed to load rest certificate: %v", err)
+		}
+	}
+	if s.cfg.prometheusTLS != nil {
+		if s.prometheusTLS, err = newCertReloader(s.cfg.prometheusTLS, s.cfg.tlsReloadInterval); err != nil {
+			gologger.Fatalf("go grpc tls: failed to load prometheus certificate: %v", err)
+		}
+	}
+}
+
+func PeerIdentityUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		return handler(withPeerIdentity(ctx), req)
+	}
+}
+
+func PeerIdentityStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, WrapServerStream(ss, withPeerIdentity(ss.Context())))
+	}
+}
+
+// withPeerIdentity puts the CN and SANs of the verified client certificate
+// into gotex. Identity metadata sent by the client is always overwritten, so
+// it can only come from the TLS handshake. Calls of the REST gateway have no
+// peer identity.
+func withPeerIdentity(ctx context.Context) context.Context {
+	var commonName string
+	var sans []string
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 && len(tlsInfo.State.VerifiedChains[0]) > 0 &&
+			!isGatewayCertificate(tlsInfo.State.VerifiedChains[0][0]) {
+			cert := tlsInfo.State.VerifiedChains[0][0]
+			commonName = cert.Subject.CommonName
+			sans = append(sans, cert.DNSNames...)
+			sans = append(sans, cert.EmailAddresses...)
+			for _, ip := range cert.IPAddresses {
+				sans = append(sans, ip.String())
+			}
+			for _, uri := range cert.URIs {
+				sans = append(sans, uri.String())
+			}
+		}
+	}
+
+	md := gotex.FromIncoming(ctx)
+	md.Set(strings.ToLower(gotex.RequestHeaderKeyPeerCommonName), commonName)
+	md.Set(strings.ToLower(gotex.RequestHeaderKeyPeerSANs), strings.Join(sans, gotex.SANSeparator))
+	return md.ToIncoming(gotex.NewContext(ctx, gotex.NewGotex(md)))
+}
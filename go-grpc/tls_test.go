@@ -0,0 +1,140 @@
+package go_grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+	"time"
+)
+
+func writeTestCertificate(t *testing.T, dir, cn string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn + ".svc"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "tls.crt")
+	keyFile = filepath.Join(dir, "tls.key")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ = x509.ParseCertificate(der)
+	return certFile, keyFile, cert
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := writeTestCertificate(t, dir, "first")
+
+	r, err := newCertReloader(&TLSConfig{CertFile: certFile, KeyFile: keyFile}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cn := r.getCertificate().Leaf.Subject.CommonName; cn != "first" {
+		t.Fatalf("common name should be 'first', got '%s'", cn)
+	}
+
+	writeTestCertificate(t, dir, "second")
+	future := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err = os.Chtimes(f, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cn := r.getCertificate().Leaf.Subject.CommonName; cn != "second" {
+		t.Errorf("common name should be 'second' after reload, got '%s'", cn)
+	}
+}
+
+func TestWithPeerIdentity(t *testing.T) {
+	_, _, cert := writeTestCertificate(t, t.TempDir(), "billing")
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("peercommonname", "spoofed"))
+	ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+	}})
+
+	gtx, ok := gotex.FromContext(withPeerIdentity(ctx))
+	if !ok {
+		t.Fatalf("gotex should be exist")
+	}
+	if gtx.PeerCommonName != "billing" {
+		t.Errorf("peer common name should be 'billing', got '%s'", gtx.PeerCommonName)
+	}
+	if !gtx.HasPeerIdentity([]string{"billing.svc"}) {
+		t.Errorf("peer SANs should contain 'billing.svc', got '%s'", gtx.PeerSANs)
+	}
+
+	spoofed := metadata.NewIncomingContext(context.Background(), metadata.Pairs("peercommonname", "spoofed"))
+	if gtx, _ = gotex.FromContext(withPeerIdentity(spoofed)); gtx.PeerCommonName != "" {
+		t.Errorf("peer common name without TLS should be empty, got '%s'", gtx.PeerCommonName)
+	}
+}
+
+func TestGatewayPeerIdentity(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := writeTestCertificate(t, dir, "orders")
+	r, err := newCertReloader(&TLSConfig{
+		CertFile:          certFile,
+		KeyFile:           keyFile,
+		ClientCAFile:      certFile,
+		RequireClientCert: true,
+	}, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clientConn, serverConn := net.Pipe()
+	server := tls.Server(serverConn, r.serverConfig())
+	client := tls.Client(clientConn, r.gatewayConfig())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.Handshake()
+	}()
+	if err = server.Handshake(); err != nil {
+		t.Fatalf("gateway should pass mTLS, got %v", err)
+	}
+	if err = <-errCh; err != nil {
+		t.Fatalf("gateway should pass mTLS, got %v", err)
+	}
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: server.ConnectionState()}})
+	gtx, _ := gotex.FromContext(withPeerIdentity(ctx))
+	if gtx.PeerCommonName != "" || gtx.PeerSANs != "" {
+		t.Errorf("gateway calls should have no peer identity, got '%s' '%s'", gtx.PeerCommonName, gtx.PeerSANs)
+	}
+}
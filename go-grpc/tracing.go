@@ -0,0 +1,137 @@
+package go_grpc
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	gotrace "pkg.tanyudii.me/go-pkg/go-trace"
+	"strings"
+)
+
+// TracingUnaryServerInterceptor starts a server span for each call, child of
+// the traceparent sent by the caller, and writes the new span context back
+// to the incoming metadata and Gotex so it is propagated further.
+func TracingUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, span := withServerSpan(ctx, info.FullMethod)
+		resp, err = handler(ctx, req)
+		endRPCSpan(span, err)
+		return resp, err
+	}
+}
+
+func TracingStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx, span := withServerSpan(ss.Context(), info.FullMethod)
+		err = handler(srv, WrapServerStream(ss, ctx))
+		endRPCSpan(span, err)
+		return err
+	}
+}
+
+// TracingUnaryClientInterceptor starts a client span for each call and sends
+// it as the traceparent of the outgoing metadata.
+func TracingUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
+		ctx, span := withClientSpan(ctx, method)
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		endRPCSpan(span, err)
+		return err
+	}
+}
+
+// TracingStreamClientInterceptor starts a client span covering the stream
+// setup only, as the end of a client stream is not observable here.
+func TracingStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (_ grpc.ClientStream, err error) {
+		ctx, span := withClientSpan(ctx, method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		endRPCSpan(span, err)
+		return cs, err
+	}
+}
+
+func withServerSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md := gotex.FromIncoming(ctx)
+	ctx, span := gotrace.Start(
+		gotrace.Extract(ctx, md),
+		fullMethod,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(rpcAttributes(fullMethod)...),
+	)
+	gotrace.Inject(ctx, md)
+	return md.ToIncoming(gotex.NewContext(ctx, gotex.NewGotex(md))), span
+}
+
+func withClientSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	ctx, span := gotrace.Start(
+		ctx,
+		method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(rpcAttributes(method)...),
+	)
+	md := gotex.ContextMD(metadata.MD(gotex.FromOutgoing(ctx)).Copy())
+	gotrace.Inject(ctx, md)
+	return md.ToOutgoing(ctx), span
+}
+
+func endRPCSpan(span trace.Span, err error) {
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(status.Code(err))))
+	gotrace.End(span, err)
+}
+
+func rpcAttributes(fullMethod string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.RPCSystemGRPC}
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		attrs = append(attrs, semconv.RPCService(name[:i]), semconv.RPCMethod(name[i+1:]))
+	}
+	return attrs
+}
+
+// tracingHTTPMiddleware starts a server span for each gateway request. The
+// span is renamed to the matched route once the handler has run.
+func tracingHTTPMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := gotrace.Propagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := gotrace.Start(
+			ctx,
+			r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		r, route := withHTTPRoute(r.WithContext(ctx))
+		rw := newResponseWriter(w)
+		h.ServeHTTP(rw, r)
+
+		if route.pattern != metricsUnknownPath {
+			span.SetName(r.Method + " " + route.pattern)
+			span.SetAttributes(semconv.HTTPRoute(route.pattern))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+		if rw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.status))
+		}
+	})
+}
+
+// tracingAnnotator passes the span of the gateway request on to the gRPC
+// call made by the generated handler.
+func tracingAnnotator(ctx context.Context, _ *http.Request) metadata.MD {
+	md := gotex.ContextMD(metadata.Pairs())
+	gotrace.Inject(ctx, md)
+	return metadata.MD(md)
+}
@@ -0,0 +1,59 @@
+package go_grpc
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+)
+
+func TestTracingUnaryServerInterceptor(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+	restore := setTracerProvider(provider)
+	defer restore()
+
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceParent))
+
+	var got *gotex.Gotex
+	var spanCtx trace.SpanContext
+	_, err := TracingUnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/order.Service/Get"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = gotex.FromContext(ctx)
+		spanCtx = trace.SpanContextFromContext(ctx)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := exp.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("should record 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "/order.Service/Get" || span.SpanKind != trace.SpanKindServer {
+		t.Errorf("unexpected span %s of kind %v", span.Name, span.SpanKind)
+	}
+	if span.Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("span should be child of the incoming traceparent, got %v", span.Parent)
+	}
+	want := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + spanCtx.SpanID().String() + "-01"
+	if got == nil || got.TraceParent != want {
+		t.Errorf("gotex traceparent should be the server span %s, got %+v", want, got)
+	}
+}
+
+func setTracerProvider(tp trace.TracerProvider) func() {
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	return func() {
+		otel.SetTracerProvider(prev)
+	}
+}
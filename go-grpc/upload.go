@@ -0,0 +1,301 @@
+package go_grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+)
+
+const (
+	DefaultUploadMaxSize   = 32 << 20
+	DefaultUploadChunkSize = 64 << 10
+
+	ErrNamePayloadTooLarge = "PAYLOAD_TOO_LARGE"
+)
+
+var (
+	ErrPayloadTooLarge = &runtime.HTTPStatusError{
+		HTTPStatus: http.StatusRequestEntityTooLarge,
+		Err:        goerr.NewBadRequestErrorWithName("[ERROR]: Payload too large", ErrNamePayloadTooLarge),
+	}
+)
+
+// UploadHandler handles a multipart/form-data request. The returned message
+// is written as the response the same way the gateway writes RPC responses.
+// No gRPC interceptor runs for it: identity metadata claimed by the client
+// is removed from ctx, so either forward the upload through
+// Upload.ClientConn, which is authenticated like any gateway call, or set
+// UploadConfig.Auth before calling service code directly.
+type UploadHandler func(ctx context.Context, u *Upload) (proto.Message, error)
+
+// UploadAuthFunc authenticates an upload before its handler runs, e.g. by
+// verifying the Authorization metadata, and returns the ctx carrying the
+// verified gotex.
+type UploadAuthFunc func(ctx context.Context) (context.Context, error)
+
+type UploadConfig struct {
+	// MaxSize bounds the whole request body, zero means DefaultUploadMaxSize.
+	MaxSize int64
+	// MaxFileSize bounds every single file, zero leaves it to MaxSize.
+	MaxFileSize int64
+	// ChunkSize is the size of the chunks UploadToClientStream sends, zero
+	// means DefaultUploadChunkSize.
+	ChunkSize int
+	// Auth runs before the request body is read, rejecting the upload with
+	// its error.
+	Auth UploadAuthFunc
+}
+
+type uploadRoute struct {
+	pattern string
+	handler UploadHandler
+	cfg     UploadConfig
+}
+
+// Upload streams the parts of a multipart/form-data request. Files are read
+// one after another with NextFile, form values sent before a file are in
+// Values once NextFile returns it.
+type Upload struct {
+	Values     url.Values
+	PathParams map[string]string
+
+	reader *multipart.Reader
+	cfg    UploadConfig
+	conn   grpc.ClientConnInterface
+}
+
+type UploadFile struct {
+	Field       string
+	FileName    string
+	ContentType string
+	Header      textproto.MIMEHeader
+
+	r    io.Reader
+	read int64
+	max  int64
+}
+
+// NextFile returns the next file of the request, discarding whatever was
+// left unread of the previous one. It returns io.EOF after the last part.
+func (u *Upload) NextFile() (*UploadFile, error) {
+	for {
+		part, err := u.reader.NextPart()
+		if err != nil {
+			return nil, uploadError(err)
+		}
+		if part.FileName() == "" {
+			b, err := io.ReadAll(part)
+			if err != nil {
+				return nil, uploadError(err)
+			}
+			u.Values.Add(part.FormName(), string(b))
+			continue
+		}
+		return &UploadFile{
+			Field:       part.FormName(),
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get(HeaderContentType),
+			Header:      part.Header,
+			r:           part,
+			max:         u.cfg.MaxFileSize,
+		}, nil
+	}
+}
+
+// ClientConn is a connection to the gRPC server of this service, e.g. to
+// forward the upload to a client-streaming RPC.
+func (u *Upload) ClientConn() grpc.ClientConnInterface {
+	return u.conn
+}
+
+func (f *UploadFile) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.read += int64(n)
+	if f.max > 0 && f.read > f.max {
+		return n, ErrPayloadTooLarge
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = uploadError(err)
+	}
+	return n, err
+}
+
+// UploadClientStream is the client side of a client-streaming RPC, as
+// generated by protoc-gen-go-grpc.
+type UploadClientStream[Req, Resp proto.Message] interface {
+	Send(Req) error
+	CloseAndRecv() (Resp, error)
+}
+
+// UploadToClientStream forwards every uploaded file in chunks to the
+// client-streaming RPC opened by open. Each file sends at least one chunk,
+// so empty files still reach the server. chunk builds the request message
+// of data read from f at offset.
+func UploadToClientStream[Req, Resp proto.Message](
+	open func(ctx context.Context, cc grpc.ClientConnInterface) (UploadClientStream[Req, Resp], error),
+	chunk func(u *Upload, f *UploadFile, data []byte, offset int64) Req,
+) UploadHandler {
+	return func(ctx context.Context, u *Upload) (proto.Message, error) {
+		stream, err := open(ctx, u.ClientConn())
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, u.cfg.ChunkSize)
+		if err = sendFiles(u, buf, func(f *UploadFile, data []byte, offset int64) error {
+			return stream.Send(chunk(u, f, data, offset))
+		}); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		// io.EOF from Send means the server ended the stream, its status is
+		// returned by CloseAndRecv
+		return stream.CloseAndRecv()
+	}
+}
+
+func sendFiles(u *Upload, buf []byte, send func(f *UploadFile, data []byte, offset int64) error) error {
+	for {
+		f, err := u.NextFile()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var offset int64
+		for {
+			n, rerr := io.ReadFull(f, buf)
+			if rerr != nil && !errors.Is(rerr, io.EOF) && !errors.Is(rerr, io.ErrUnexpectedEOF) {
+				return rerr
+			}
+			if n > 0 || offset == 0 {
+				if err = send(f, buf[:n], offset); err != nil {
+					return err
+				}
+			}
+			offset += int64(n)
+			if rerr != nil {
+				break
+			}
+		}
+	}
+}
+
+func (r uploadRoute) handle(mux *runtime.ServeMux, conn grpc.ClientConnInterface) runtime.HandlerFunc {
+	cfg := r.cfg
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultUploadMaxSize
+	}
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = DefaultUploadChunkSize
+	}
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		_, marshaler := runtime.MarshalerForRequest(mux, req)
+		ctx, err := runtime.AnnotateContext(req.Context(), mux, req, r.pattern, runtime.WithHTTPPathPattern(r.pattern))
+		if err != nil {
+			runtime.HTTPError(req.Context(), mux, marshaler, w, req, err)
+			return
+		}
+		// the handler may call the service code directly or a gRPC client,
+		// so the metadata is both incoming and outgoing. Outgoing calls are
+		// authenticated by the gRPC server, the incoming side only keeps
+		// what the client cannot claim.
+		md := withoutIdentity(gotex.FromOutgoing(ctx))
+		ctx = md.ToIncoming(gotex.NewContext(ctx, gotex.NewGotex(md)))
+		if cfg.Auth != nil {
+			if ctx, err = cfg.Auth(ctx); err != nil {
+				runtime.HTTPError(req.Context(), mux, marshaler, w, req, err)
+				return
+			}
+		}
+
+		if req.ContentLength > cfg.MaxSize {
+			runtime.HTTPError(ctx, mux, marshaler, w, req, ErrPayloadTooLarge)
+			return
+		}
+		req.Body = http.MaxBytesReader(w, req.Body, cfg.MaxSize)
+		reader, err := req.MultipartReader()
+		if err != nil {
+			runtime.HTTPError(ctx, mux, marshaler, w, req, uploadError(err))
+			return
+		}
+
+		var header metadata.MD
+		resp, err := r.handler(ctx, &Upload{
+			Values:     make(url.Values),
+			PathParams: pathParams,
+			reader:     reader,
+			cfg:        cfg,
+			conn:       headerClientConn{ClientConnInterface: conn, header: &header},
+		})
+		if err != nil {
+			runtime.HTTPError(ctx, mux, marshaler, w, req, uploadError(err))
+			return
+		}
+		ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{HeaderMD: header})
+		runtime.ForwardResponseMessage(ctx, mux, marshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+	}
+}
+
+// headerClientConn collects the response headers of the calls made during
+// an upload, so they are forwarded like the gateway forwards them.
+type headerClientConn struct {
+	grpc.ClientConnInterface
+	header *metadata.MD
+}
+
+func (c headerClientConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	return c.ClientConnInterface.Invoke(ctx, method, args, reply, append(opts, grpc.Header(c.header))...)
+}
+
+func (c headerClientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return c.ClientConnInterface.NewStream(ctx, desc, method, append(opts, grpc.Header(c.header))...)
+}
+
+var (
+	// identityHeaders are claimed by the client until the auth of the
+	// service verified them.
+	identityHeaders = []string{
+		gotex.RequestHeaderKeyUserID,
+		gotex.RequestHeaderKeyUserName,
+		gotex.RequestHeaderKeyUserEmail,
+		gotex.RequestHeaderKeyUserType,
+		gotex.RequestHeaderKeyCompanyID,
+		gotex.RequestHeaderKeyCompanyName,
+		gotex.RequestHeaderKeyPermissions,
+		gotex.RequestHeaderKeyScopes,
+		gotex.RequestHeaderKeyClientID,
+		gotex.RequestHeaderKeyClientName,
+		gotex.RequestHeaderKeyPeerCommonName,
+		gotex.RequestHeaderKeyPeerSANs,
+	}
+)
+
+// withoutIdentity returns a copy of md without the identityHeaders.
+func withoutIdentity(md gotex.ContextMD) gotex.ContextMD {
+	md = gotex.ContextMD(metadata.MD(md).Copy())
+	for _, h := range identityHeaders {
+		md.Delete(strings.ToLower(h))
+	}
+	return md
+}
+
+func uploadError(err error) error {
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytes):
+		return ErrPayloadTooLarge
+	case errors.Is(err, http.ErrNotMultipart), errors.Is(err, http.ErrMissingBoundary), errors.Is(err, io.ErrUnexpectedEOF):
+		return goerr.NewBadRequestError("[ERROR]: Invalid multipart request: " + err.Error())
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+package go_grpc
+
+import (
+	"bytes"
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+	"testing"
+)
+
+func newUploadRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for k, v := range fields {
+		_ = mw.WriteField(k, v)
+	}
+	for name, content := range files {
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, _ = fw.Write([]byte(content))
+	}
+	_ = mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/v1/files/docs", body)
+	r.Header.Set(HeaderContentType, mw.FormDataContentType())
+	r.Header.Set(gotex.RequestHeaderKeyUserID, "spoofed")
+	r.Header.Set(gotex.RequestHeaderKeyRequestID, "req-1")
+	return r
+}
+
+func serveUpload(t *testing.T, r *http.Request, h UploadHandler, cfg UploadConfig) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := runtime.NewServeMux(
+		runtime.WithErrorHandler(MuxErrorHandler),
+		runtime.WithIncomingHeaderMatcher(MuxIncomingHeaderMatcher),
+	)
+	route := uploadRoute{pattern: "/v1/files/{folder}", handler: h, cfg: cfg}
+	if err := mux.HandlePath(http.MethodPost, route.pattern, route.handle(mux, nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestUploadHandler(t *testing.T) {
+	var got []string
+	h := func(ctx context.Context, u *Upload) (proto.Message, error) {
+		if userID, _ := gotex.GetUserID(ctx); userID != "" {
+			t.Errorf("client claimed user should not be trusted, got user %q", userID)
+		}
+		if gtx, _ := gotex.FromContext(ctx); gtx.RequestID != "req-1" {
+			t.Errorf("other headers should reach the metadata, got request id %q", gtx.RequestID)
+		}
+		for {
+			f, err := u.NextFile()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			b, _ := io.ReadAll(f)
+			got = append(got, f.FileName+":"+string(b))
+		}
+		return wrapperspb.String(u.PathParams["folder"] + "/" + u.Values.Get("title")), nil
+	}
+
+	r := newUploadRequest(t, map[string]string{"title": "report"}, map[string]string{"a.txt": "hello"})
+	rec := serveUpload(t, r, h, UploadConfig{})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), "docs/report") {
+		t.Errorf("unexpected body %s", rec.Body)
+	}
+	if len(got) != 1 || got[0] != "a.txt:hello" {
+		t.Errorf("unexpected files %v", got)
+	}
+}
+
+func TestUploadHandlerAuth(t *testing.T) {
+	auth := func(ctx context.Context) (context.Context, error) {
+		md := gotex.FromIncoming(ctx)
+		if md.Get(strings.ToLower(gotex.RequestHeaderKeyAuthorization)) != "Bearer token" {
+			return nil, goerr.NewUnauthenticatedError("invalid token")
+		}
+		md.Set(strings.ToLower(gotex.RequestHeaderKeyUserID), "user-1")
+		return md.ToIncoming(gotex.NewContext(ctx, gotex.NewGotex(md))), nil
+	}
+	h := func(ctx context.Context, u *Upload) (proto.Message, error) {
+		userID, _ := gotex.GetUserID(ctx)
+		return wrapperspb.String(userID), nil
+	}
+
+	r := newUploadRequest(t, nil, map[string]string{"a.txt": "hello"})
+	if rec := serveUpload(t, r, h, UploadConfig{Auth: auth}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("upload without token should be rejected, got %d", rec.Code)
+	}
+
+	r = newUploadRequest(t, nil, map[string]string{"a.txt": "hello"})
+	r.Header.Set(gotex.RequestHeaderKeyAuthorization, "Bearer token")
+	rec := serveUpload(t, r, h, UploadConfig{Auth: auth})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "user-1") {
+		t.Errorf("handler should get the verified user, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUploadHandlerTooLarge(t *testing.T) {
+	h := func(ctx context.Context, u *Upload) (proto.Message, error) {
+		f, err := u.NextFile()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.ReadAll(f); err != nil {
+			return nil, err
+		}
+		return wrapperspb.String("ok"), nil
+	}
+
+	r := newUploadRequest(t, nil, map[string]string{"a.txt": strings.Repeat("x", 100)})
+	if rec := serveUpload(t, r, h, UploadConfig{MaxFileSize: 10}); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("file over MaxFileSize should be rejected, got %d", rec.Code)
+	}
+	r = newUploadRequest(t, nil, map[string]string{"a.txt": strings.Repeat("x", 100)})
+	if rec := serveUpload(t, r, h, UploadConfig{MaxSize: 50}); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("request over MaxSize should be rejected, got %d", rec.Code)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/v1/files/docs", strings.NewReader("{}"))
+	if rec := serveUpload(t, r, h, UploadConfig{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("non multipart request should be rejected, got %d", rec.Code)
+	}
+}
+
+func TestHTTPBodyMarshaler(t *testing.T) {
+	m := newHTTPBodyMarshaler(&runtime.JSONPb{})
+	body := &httpbody.HttpBody{ContentType: "text/csv", Data: []byte("a,b")}
+	if ct := m.ContentType(body); ct != "text/csv" {
+		t.Errorf("unexpected content type %s", ct)
+	}
+	if b, _ := m.Marshal(body); string(b) != "a,b" {
+		t.Errorf("http body should be written as is, got %q", b)
+	}
+	if b, _ := m.Marshal(map[string]interface{}{"result": wrapperspb.String("x")}); !bytes.HasSuffix(b, []byte("\n")) {
+		t.Errorf("streamed messages should stay delimited, got %q", b)
+	}
+	if d, ok := m.(runtime.Delimited); !ok || len(d.Delimiter()) != 0 {
+		t.Error("stream delimiter should be empty")
+	}
+	if h, _ := MuxOutgoingHeaderMatcher("content-disposition"); h != HeaderContentDisposition {
+		t.Errorf("unexpected header %s", h)
+	}
+}
@@ -0,0 +1,102 @@
+package go_grpc
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	ErrInvalidConfig = errors.New("[ERROR]: Invalid go grpc config")
+)
+
+// Validate reports every problem of the config at once, including errors of
+// FromEnv and FromFile. RunServers refuses to start with an invalid config.
+func (s *service) Validate() error {
+	return s.cfg.validate()
+}
+
+func (c *Config) validate() error {
+	errs := append([]error{}, c.errs...)
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidConfig}, args...)...))
+	}
+
+	ports := map[string]string{}
+	checkPort := func(name, port string) {
+		if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+			invalid("%s port %q is not a port number", name, port)
+			return
+		}
+		if other, ok := ports[port]; ok && port != "0" {
+			invalid("%s port %s is used by the %s server as well", name, port, other)
+			return
+		}
+		ports[port] = name
+	}
+	if c.singlePort != "" {
+		checkPort("single", c.singlePort)
+	} else {
+		checkPort("grpc", c.gRPCPort)
+		checkPort("rest", c.restPort)
+	}
+	if c.enablePrometheus && (c.singlePort == "" || !c.singlePortMetrics) {
+		checkPort("prometheus", c.prometheusPort)
+	}
+
+	if c.singlePortMetrics && c.singlePort == "" {
+		invalid("single port metrics need a single port")
+	}
+	if c.admin != nil && !c.enablePrometheus {
+		invalid("admin endpoints are served on the metrics port, which needs prometheus enabled")
+	}
+	if c.admin != nil && c.admin.Token == "" {
+		invalid("admin endpoints need a token")
+	}
+	if c.enableCORS && c.corsPolicy.AllowCredentials && c.corsPolicy.allowsAllOrigins() {
+		invalid("cors credentials can not be allowed for any origin")
+	}
+	if c.accessLog != nil && (c.accessLog.SampleRate < 0 || c.accessLog.SampleRate > 1) {
+		invalid("access log sample rate %v is not between 0 and 1", c.accessLog.SampleRate)
+	}
+	if c.healthCheckTimeout > c.healthCheckInterval {
+		invalid("health check timeout %v exceeds the interval %v", c.healthCheckTimeout, c.healthCheckInterval)
+	}
+	if c.shutdownDrainPeriod >= c.shutdownTimeout {
+		invalid("shutdown drain period %v is not shorter than the shutdown timeout %v", c.shutdownDrainPeriod, c.shutdownTimeout)
+	}
+	if c.maxRecvMsgSize <= 0 || c.maxSendMsgSize <= 0 {
+		invalid("message size limits %d and %d must be positive", c.maxRecvMsgSize, c.maxSendMsgSize)
+	}
+	if c.gzipLevel != nil && (*c.gzipLevel < gzip.DefaultCompression || *c.gzipLevel > gzip.BestCompression) {
+		invalid("gzip level %d is not between %d and %d", *c.gzipLevel, gzip.DefaultCompression, gzip.BestCompression)
+	}
+	if c.keepalive != nil && c.keepalive.MaxConnectionAgeGrace > 0 && c.keepalive.MaxConnectionAge == 0 {
+		invalid("max connection age grace needs a max connection age")
+	}
+	if c.defaultTimeout < 0 {
+		invalid("default timeout %v is negative", c.defaultTimeout)
+	}
+	tlsConfigs := []struct {
+		name string
+		cfg  *TLSConfig
+	}{
+		{"grpc", c.gRPCTLS},
+		{"rest", c.restTLS},
+		{"prometheus", c.prometheusTLS},
+	}
+	for _, t := range tlsConfigs {
+		if t.cfg == nil {
+			continue
+		}
+		if t.cfg.CertFile == "" || t.cfg.KeyFile == "" {
+			invalid("%s tls needs both a cert and a key file", t.name)
+		}
+		if t.cfg.RequireClientCert && t.cfg.ClientCAFile == "" {
+			invalid("%s tls requires client certs without a client ca file", t.name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,79 @@
+package go_idempotency
+
+import (
+	"time"
+)
+
+const (
+	DefaultTTL     = 24 * time.Hour
+	DefaultLockTTL = 30 * time.Second
+)
+
+type Config struct {
+	store       Store
+	ttl         time.Duration
+	lockTTL     time.Duration
+	skipMethods map[string]bool
+	failOpen    bool
+}
+
+type ConfigFunc func(c *Config)
+
+func WithStore(s Store) ConfigFunc {
+	return func(c *Config) {
+		c.store = s
+	}
+}
+
+// TTL is how long a finished result is replayed for.
+func TTL(d time.Duration) ConfigFunc {
+	if d <= 0 {
+		d = DefaultTTL
+	}
+	return func(c *Config) {
+		c.ttl = d
+	}
+}
+
+// LockTTL is how long a key stays in progress. It should be longer than the
+// slowest call, after that a retry runs the call again.
+func LockTTL(d time.Duration) ConfigFunc {
+	if d <= 0 {
+		d = DefaultLockTTL
+	}
+	return func(c *Config) {
+		c.lockTTL = d
+	}
+}
+
+func SkipMethods(methods ...string) ConfigFunc {
+	return func(c *Config) {
+		for _, m := range methods {
+			c.skipMethods[m] = true
+		}
+	}
+}
+
+// FailOpen runs the call without idempotency when the store itself errors,
+// e.g. when redis is unreachable.
+func FailOpen(f bool) ConfigFunc {
+	return func(c *Config) {
+		c.failOpen = f
+	}
+}
+
+func generate(args ...ConfigFunc) *Config {
+	c := &Config{
+		ttl:         DefaultTTL,
+		lockTTL:     DefaultLockTTL,
+		skipMethods: make(map[string]bool),
+		failOpen:    true,
+	}
+	for i := range args {
+		args[i](c)
+	}
+	if c.store == nil {
+		c.store = NewLocalStore()
+	}
+	return c
+}
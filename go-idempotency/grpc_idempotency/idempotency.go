@@ -0,0 +1,152 @@
+package grpc_idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	goidempotency "pkg.tanyudii.me/go-pkg/go-idempotency"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"time"
+)
+
+const (
+	storeTimeout = 5 * time.Second
+)
+
+// UnaryInterceptor stores the result of calls carrying an Idempotency-Key
+// header and replays it to retries with the same key and request. Errors
+// meaning the call did not run, e.g. Unavailable, are not stored. It must
+// run after authentication, keys are scoped to the identity auth verified and
+// calls without one are not deduplicated.
+func UnaryInterceptor(svc goidempotency.Service) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md := gotex.FromIncoming(ctx)
+		value := md.Get(goidempotency.HeaderIdempotencyKey)
+		msg, ok := req.(proto.Message)
+		actor := gotex.VerifiedIdentity(ctx)
+		if value == "" || !ok || actor == nil || svc.IsSkipped(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		hash, err := requestHash(msg)
+		if err != nil {
+			return nil, err
+		}
+		key := goidempotency.Key{
+			Method:  info.FullMethod,
+			Subject: subject(actor),
+			Value:   value,
+		}
+		rec, claimed, err := svc.Begin(ctx, key, hash)
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			return replay(rec)
+		}
+
+		resp, err := handler(ctx, req)
+		if claimed {
+			finish(svc, key, hash, resp, err)
+		}
+		return resp, err
+	}
+}
+
+// finish runs on a fresh context, the one of the call may be done already.
+func finish(svc goidempotency.Service, key goidempotency.Key, hash string, resp interface{}, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if isRetryable(err) {
+		if rerr := svc.Release(ctx, key); rerr != nil {
+			gologger.Errorf("go idempotency: failed to release key %v", rerr)
+		}
+		return
+	}
+
+	rec, rerr := newRecord(hash, resp, err)
+	if rerr == nil {
+		rerr = svc.Complete(ctx, key, rec)
+	}
+	if rerr != nil {
+		gologger.Errorf("go idempotency: failed to store result %v", rerr)
+		_ = svc.Release(ctx, key)
+	}
+}
+
+func newRecord(hash string, resp interface{}, err error) (*goidempotency.Record, error) {
+	rec := &goidempotency.Record{RequestHash: hash}
+	if err != nil {
+		b, merr := proto.Marshal(grpcstatus.Convert(err).Proto())
+		if merr != nil {
+			return nil, merr
+		}
+		rec.Status = b
+		return rec, nil
+	}
+	if msg, ok := resp.(proto.Message); ok {
+		a, merr := anypb.New(msg)
+		if merr != nil {
+			return nil, merr
+		}
+		b, merr := proto.Marshal(a)
+		if merr != nil {
+			return nil, merr
+		}
+		rec.Response = b
+	}
+	return rec, nil
+}
+
+func replay(rec *goidempotency.Record) (interface{}, error) {
+	if len(rec.Status) != 0 {
+		s := &status.Status{}
+		if err := proto.Unmarshal(rec.Status, s); err != nil {
+			return nil, err
+		}
+		return nil, goerr.FromStatus(grpcstatus.FromProto(s))
+	}
+	if len(rec.Response) == 0 {
+		return nil, nil
+	}
+	a := &anypb.Any{}
+	if err := proto.Unmarshal(rec.Response, a); err != nil {
+		return nil, err
+	}
+	return a.UnmarshalNew()
+}
+
+func requestHash(msg proto.Message) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func subject(actor *gotex.Gotex) string {
+	if actor.UserID != "" {
+		return "user:" + actor.UserID
+	}
+	return "client:" + actor.ClientID
+}
+
+func isRetryable(err error) bool {
+	switch grpcstatus.Code(err) {
+	// Aborted is left out, it is the code of a ConflictError returned by the
+	// handler, a result to replay like any other
+	case codes.Canceled, codes.DeadlineExceeded, codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package grpc_idempotency
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	goidempotency "pkg.tanyudii.me/go-pkg/go-idempotency"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+)
+
+func TestUnaryInterceptorReplay(t *testing.T) {
+	interceptor := UnaryInterceptor(goidempotency.NewService())
+	info := &grpc.UnaryServerInfo{FullMethod: "/order.Service/Create"}
+	ctx := verifiedContext("user-1", metadata.Pairs("idempotency-key", "key-1"))
+
+	var calls int
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return wrapperspb.String("order-1"), nil
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := interceptor(ctx, wrapperspb.String("create"), info, handler)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !proto.Equal(resp.(proto.Message), wrapperspb.String("order-1")) {
+			t.Errorf("response should be replayed, got %v", resp)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler should run once, ran %d times", calls)
+	}
+
+	_, err := interceptor(ctx, wrapperspb.String("create other"), info, handler)
+	if !goerr.IsConflictError(err) || !goerr.IsErrorName(err, "IDEMPOTENCY_KEY_REUSED") {
+		t.Errorf("reused key with other payload should conflict, got %v", err)
+	}
+
+	otherUser := verifiedContext("user-2", metadata.Pairs("idempotency-key", "key-1"))
+	if _, err = interceptor(otherUser, wrapperspb.String("create"), info, handler); err != nil || calls != 2 {
+		t.Errorf("key should be scoped per user, got %v after %d calls", err, calls)
+	}
+}
+
+func TestUnaryInterceptorReplayError(t *testing.T) {
+	interceptor := UnaryInterceptor(goidempotency.NewService())
+	info := &grpc.UnaryServerInfo{FullMethod: "/order.Service/Create"}
+	ctx := verifiedContext("user-1", metadata.Pairs("idempotency-key", "key-1"))
+
+	var calls int
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, goerr.NewBadRequestErrorWithName("out of stock", "OUT_OF_STOCK")
+	}
+	for i := 0; i < 2; i++ {
+		_, err := interceptor(ctx, wrapperspb.String("create"), info, handler)
+		if !goerr.IsBadRequestError(err) || !goerr.IsErrorName(err, "OUT_OF_STOCK") {
+			t.Errorf("error should be replayed, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler should run once, ran %d times", calls)
+	}
+}
+
+func TestUnaryInterceptorReplayConflict(t *testing.T) {
+	interceptor := UnaryInterceptor(goidempotency.NewService())
+	info := &grpc.UnaryServerInfo{FullMethod: "/order.Service/Create"}
+	ctx := verifiedContext("user-1", metadata.Pairs("idempotency-key", "key-1"))
+
+	var calls int
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, goerr.NewConflictErrorWithName("already paid", "ALREADY_PAID")
+	}
+	for i := 0; i < 2; i++ {
+		_, err := interceptor(ctx, wrapperspb.String("create"), info, handler)
+		if !goerr.IsConflictError(err) || !goerr.IsErrorName(err, "ALREADY_PAID") {
+			t.Errorf("conflict should be replayed, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler should run once, ran %d times", calls)
+	}
+}
+
+func TestUnaryInterceptorSubject(t *testing.T) {
+	interceptor := UnaryInterceptor(goidempotency.NewService())
+	info := &grpc.UnaryServerInfo{FullMethod: "/order.Service/Create"}
+
+	var calls int
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return wrapperspb.String("order-1"), nil
+	}
+
+	unauthenticated := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"idempotency-key", "key-1",
+		"userid", "user-1",
+	))
+	for i := 0; i < 2; i++ {
+		_, _ = interceptor(unauthenticated, wrapperspb.String("create"), info, handler)
+	}
+	if calls != 2 {
+		t.Errorf("calls without a verified identity should not be deduplicated, ran %d times", calls)
+	}
+
+	calls = 0
+	_, _ = interceptor(verifiedContext("user-1", metadata.Pairs("idempotency-key", "key-1")), wrapperspb.String("create"), info, handler)
+	spoofed := verifiedContext("user-2", metadata.Pairs(
+		"idempotency-key", "key-1",
+		"userid", "user-1",
+	))
+	_, _ = interceptor(spoofed, wrapperspb.String("create"), info, handler)
+	if calls != 2 {
+		t.Errorf("a spoofed user header should not replay the result of another user, ran %d times", calls)
+	}
+}
+
+func verifiedContext(userID string, md metadata.MD) context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	return gotex.SetVerifiedIdentity(ctx, &gotex.Gotex{UserID: userID})
+}
@@ -0,0 +1,100 @@
+package go_idempotency
+
+import (
+	"context"
+	"fmt"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+)
+
+const (
+	HeaderIdempotencyKey = "idempotency-key"
+	keyPrefix            = "idempotency"
+)
+
+var (
+	ErrKeyReused  = goerr.NewConflictErrorWithName("[ERROR]: Idempotency key reused with a different request", "IDEMPOTENCY_KEY_REUSED")
+	ErrInProgress = goerr.NewConflictErrorWithName("[ERROR]: Request with the same idempotency key is in progress", "IDEMPOTENCY_IN_PROGRESS")
+)
+
+// Key identifies a request: the idempotency key sent by the client, scoped
+// to the method and the user or client calling it.
+type Key struct {
+	Method  string
+	Subject string
+	Value   string
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s:%s:%s:%s", keyPrefix, k.Method, k.Subject, k.Value)
+}
+
+type Service interface {
+	IsSkipped(method string) bool
+	// Begin claims key for a request hashing to requestHash. It returns the
+	// finished record of an earlier request with the same key to replay, or
+	// whether the key was claimed. A claimed key must be completed or
+	// released once the request is done.
+	Begin(ctx context.Context, key Key, requestHash string) (rec *Record, claimed bool, err error)
+	Complete(ctx context.Context, key Key, rec *Record) error
+	Release(ctx context.Context, key Key) error
+}
+
+type service struct {
+	cfg *Config
+}
+
+func NewService(args ...ConfigFunc) Service {
+	return &service{
+		cfg: generate(args...),
+	}
+}
+
+func (s *service) IsSkipped(method string) bool {
+	return s.cfg.skipMethods[method]
+}
+
+func (s *service) Begin(ctx context.Context, key Key, requestHash string) (*Record, bool, error) {
+	// a second round covers a record expiring between Get and Create
+	for i := 0; i < 2; i++ {
+		rec, err := s.cfg.store.Get(ctx, key.String())
+		if err != nil {
+			return nil, false, s.storeFailed(err)
+		}
+		if rec != nil {
+			if rec.RequestHash != requestHash {
+				return nil, false, ErrKeyReused
+			}
+			if !rec.Done {
+				return nil, false, ErrInProgress
+			}
+			return rec, false, nil
+		}
+
+		claimed, err := s.cfg.store.Create(ctx, key.String(), &Record{RequestHash: requestHash}, s.cfg.lockTTL)
+		if err != nil {
+			return nil, false, s.storeFailed(err)
+		}
+		if claimed {
+			return nil, true, nil
+		}
+	}
+	return nil, false, ErrInProgress
+}
+
+func (s *service) Complete(ctx context.Context, key Key, rec *Record) error {
+	rec.Done = true
+	return s.cfg.store.Set(ctx, key.String(), rec, s.cfg.ttl)
+}
+
+func (s *service) Release(ctx context.Context, key Key) error {
+	return s.cfg.store.Delete(ctx, key.String())
+}
+
+func (s *service) storeFailed(err error) error {
+	if s.cfg.failOpen {
+		gologger.Errorf("go idempotency: store failed, running request without idempotency %v", err)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package go_idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"sync"
+	"time"
+)
+
+// Record is what is kept per idempotency key. It is in progress until Done.
+type Record struct {
+	RequestHash string `json:"request_hash"`
+	Done        bool   `json:"done"`
+	Response    []byte `json:"response,omitempty"`
+	Status      []byte `json:"status,omitempty"`
+}
+
+type Store interface {
+	// Get returns the record of key, or nil when there is none.
+	Get(ctx context.Context, key string) (*Record, error)
+	// Create stores rec under key unless the key already exists.
+	Create(ctx context.Context, key string, rec *Record, ttl time.Duration) (bool, error)
+	Set(ctx context.Context, key string, rec *Record, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type redisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore keeps records in redis, shared by every instance of a
+// service, e.g. with a client from connection/redis.
+func NewRedisStore(client *redis.Client) Store {
+	return &redisStore{client: client}
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) (*Record, error) {
+	b, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	rec := &Record{}
+	if err = json.Unmarshal(b, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (s *redisStore) Create(ctx context.Context, key string, rec *Record, ttl time.Duration) (bool, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return false, err
+	}
+	return s.client.SetNX(ctx, key, b, ttl).Result()
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, rec *Record, ttl time.Duration) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, key, b, ttl).Err()
+}
+
+func (s *redisStore) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
+
+// localStore keeps records in process, for single instances and tests.
+type localStore struct {
+	mu      sync.Mutex
+	records map[string]*localRecord
+}
+
+type localRecord struct {
+	rec       Record
+	expiresAt time.Time
+}
+
+func NewLocalStore() Store {
+	return &localStore{records: make(map[string]*localRecord)}
+}
+
+func (s *localStore) Get(_ context.Context, key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.get(key)
+	if !ok {
+		return nil, nil
+	}
+	rec := r.rec
+	return &rec, nil
+}
+
+func (s *localStore) Create(_ context.Context, key string, rec *Record, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.get(key); ok {
+		return false, nil
+	}
+	s.records[key] = &localRecord{rec: *rec, expiresAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (s *localStore) Set(_ context.Context, key string, rec *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = &localRecord{rec: *rec, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *localStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func (s *localStore) get(key string) (*localRecord, bool) {
+	r, ok := s.records[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(r.expiresAt) {
+		delete(s.records, key)
+		return nil, false
+	}
+	return r, true
+}
@@ -24,6 +24,14 @@ func GetLogger() *logrus.Logger {
 	return log
 }
 
+func SetLevel(level logrus.Level) {
+	logger.SetLevel(level)
+}
+
+func GetLevel() logrus.Level {
+	return logger.GetLevel()
+}
+
 func WithField(key string, value interface{}) *logrus.Entry {
 	return logger.WithField(key, value)
 }
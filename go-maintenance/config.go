@@ -0,0 +1,76 @@
+package go_maintenance
+
+import (
+	"time"
+)
+
+const (
+	DefaultRefreshInterval = 5 * time.Second
+	DefaultRetryAfter      = time.Minute
+	DefaultMessage         = "service is under maintenance, please retry later"
+)
+
+type Config struct {
+	source          Source
+	refreshInterval time.Duration
+	allowMethods    []string
+	message         string
+	retryAfter      time.Duration
+}
+
+type ConfigFunc func(c *Config)
+
+func WithSource(s Source) ConfigFunc {
+	return func(c *Config) {
+		c.source = s
+	}
+}
+
+// RefreshInterval is how long the state of the source is cached, so redis
+// or the file are not read on every call.
+func RefreshInterval(d time.Duration) ConfigFunc {
+	return func(c *Config) {
+		c.refreshInterval = d
+	}
+}
+
+// AllowMethods keeps the methods matching one of the patterns available
+// during maintenance, with the same patterns as State.Methods.
+func AllowMethods(patterns ...string) ConfigFunc {
+	return func(c *Config) {
+		c.allowMethods = append(c.allowMethods, patterns...)
+	}
+}
+
+// Message is returned when the state has no message of its own.
+func Message(msg string) ConfigFunc {
+	return func(c *Config) {
+		c.message = msg
+	}
+}
+
+// RetryAfter is the retry hint when the state has none of its own.
+func RetryAfter(d time.Duration) ConfigFunc {
+	return func(c *Config) {
+		c.retryAfter = d
+	}
+}
+
+func generate(args ...ConfigFunc) *Config {
+	c := &Config{
+		refreshInterval: DefaultRefreshInterval,
+		message:         DefaultMessage,
+		retryAfter:      DefaultRetryAfter,
+	}
+	for i := range args {
+		args[i](c)
+	}
+	if c.source == nil {
+		c.source = NewLocalSource()
+	}
+
+	// never reject grpc health check
+	c.allowMethods = append(c.allowMethods, "/grpc.health.v1.Health/*")
+
+	return c
+}
@@ -0,0 +1,27 @@
+package gin_maintenance
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gomaintenance "pkg.tanyudii.me/go-pkg/go-maintenance"
+	"strconv"
+)
+
+// Maintenance rejects requests under maintenance, matching the patterns
+// against "[METHOD] /full/path".
+func Maintenance(svc gomaintenance.Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		method := fmt.Sprintf("[%s] %s", c.Request.Method, c.FullPath())
+		if err := svc.Check(c.Request.Context(), method); err != nil {
+			if retryAfter := goerr.GetRetryAfter(err); retryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			}
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,25 @@
+package grpc_maintenance
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	gomaintenance "pkg.tanyudii.me/go-pkg/go-maintenance"
+)
+
+func UnaryInterceptor(svc gomaintenance.Service) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := svc.Check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamInterceptor(svc gomaintenance.Service) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := svc.Check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
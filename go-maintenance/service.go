@@ -0,0 +1,147 @@
+package go_maintenance
+
+import (
+	"context"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ErrNameMaintenance = "MAINTENANCE"
+)
+
+type Service interface {
+	// Check returns a ServiceUnavailableError when method is under
+	// maintenance.
+	Check(ctx context.Context, method string) error
+	// State returns the current state of the source.
+	State(ctx context.Context) (*State, error)
+	// Set switches the maintenance in the source and applies it right away.
+	Set(ctx context.Context, state *State) error
+}
+
+type service struct {
+	cfg *Config
+
+	mu        sync.Mutex
+	state     *State
+	refreshAt time.Time
+	// refreshing is closed once the running refresh is done
+	refreshing chan struct{}
+	// version counts Set calls, so a refresh started before one does not
+	// overwrite its state
+	version uint64
+}
+
+func NewService(args ...ConfigFunc) Service {
+	return &service{
+		cfg: generate(args...),
+	}
+}
+
+func (s *service) Check(ctx context.Context, method string) error {
+	state := s.current(ctx)
+	if !state.Enabled || matchAny(s.cfg.allowMethods, method) {
+		return nil
+	}
+	if len(state.Methods) > 0 && !matchAny(state.Methods, method) {
+		return nil
+	}
+
+	msg := state.Message
+	if msg == "" {
+		msg = s.cfg.message
+	}
+	retryAfter := state.retryAfter()
+	if retryAfter <= 0 {
+		retryAfter = s.cfg.retryAfter
+	}
+	return goerr.NewServiceUnavailableErrorWithNameAndRetryAfter(msg, ErrNameMaintenance, retryAfter)
+}
+
+func (s *service) State(ctx context.Context) (*State, error) {
+	return s.cfg.source.Get(ctx)
+}
+
+func (s *service) Set(ctx context.Context, state *State) error {
+	if err := s.cfg.source.Set(ctx, state); err != nil {
+		return err
+	}
+	gologger.Warnf("go maintenance: switched enabled=%t methods=%v", state.Enabled, state.Methods)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached := *state
+	s.state = &cached
+	s.version++
+	s.refreshAt = time.Now().Add(s.cfg.refreshInterval)
+	return nil
+}
+
+// current returns the cached state, refreshed from the source once the
+// refresh interval passed. A single caller reads the source, outside the
+// lock, while the others keep using the last state. When the source fails
+// the last known state is kept, which is disabled until the source has been
+// read once.
+func (s *service) current(ctx context.Context) *State {
+	s.mu.Lock()
+	if s.state != nil && (s.refreshing != nil || time.Now().Before(s.refreshAt)) {
+		defer s.mu.Unlock()
+		return s.state
+	}
+	if done := s.refreshing; done != nil {
+		// nothing to fall back to before the first read, wait for it
+		s.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		return s.last()
+	}
+	done := make(chan struct{})
+	s.refreshing = done
+	version := s.version
+	s.mu.Unlock()
+
+	state, err := s.cfg.source.Get(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = nil
+	close(done)
+	s.refreshAt = time.Now().Add(s.cfg.refreshInterval)
+	if err != nil {
+		gologger.Errorf("go maintenance: failed to get state, keeping the last one %v", err)
+	} else if version == s.version {
+		s.state = state
+	}
+	if s.state == nil {
+		s.state = &State{}
+	}
+	return s.state
+}
+
+func (s *service) last() *State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		return &State{}
+	}
+	return s.state
+}
+
+func matchAny(patterns []string, method string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(method, prefix) {
+				return true
+			}
+		} else if p == method {
+			return true
+		}
+	}
+	return false
+}
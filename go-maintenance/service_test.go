@@ -0,0 +1,119 @@
+package go_maintenance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	"testing"
+	"time"
+)
+
+func TestServiceCheck(t *testing.T) {
+	svc := NewService(AllowMethods("/order.Service/Get"))
+	ctx := context.Background()
+
+	if err := svc.Check(ctx, "/order.Service/Create"); err != nil {
+		t.Fatalf("disabled maintenance should allow, got %v", err)
+	}
+
+	if err := svc.Set(ctx, &State{
+		Enabled:           true,
+		Methods:           []string{"/order.Service/*"},
+		Message:           "migrating orders",
+		RetryAfterSeconds: 30,
+	}); err != nil {
+		t.Fatalf("set failed %v", err)
+	}
+
+	err := svc.Check(ctx, "/order.Service/Create")
+	if !goerr.IsServiceUnavailableError(err) {
+		t.Fatalf("error should be ServiceUnavailableError, got %v", err)
+	}
+	if err.Error() != "migrating orders" {
+		t.Errorf("message should be from the state, got %q", err.Error())
+	}
+	if retryAfter := goerr.GetRetryAfter(err); retryAfter != 30*time.Second {
+		t.Errorf("retry after should be 30s, got %v", retryAfter)
+	}
+
+	for _, method := range []string{"/order.Service/Get", "/user.Service/Create", "/grpc.health.v1.Health/Check"} {
+		if err = svc.Check(ctx, method); err != nil {
+			t.Errorf("%s should be allowed, got %v", method, err)
+		}
+	}
+}
+
+type slowSource struct {
+	Source
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *slowSource) Get(ctx context.Context) (*State, error) {
+	s.entered <- struct{}{}
+	<-s.release
+	return s.Source.Get(ctx)
+}
+
+func TestServiceRefreshOutsideLock(t *testing.T) {
+	src := &slowSource{Source: NewLocalSource(), entered: make(chan struct{}, 1), release: make(chan struct{})}
+	svc := NewService(WithSource(src), RefreshInterval(time.Millisecond))
+	ctx := context.Background()
+	if err := svc.Set(ctx, &State{Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	refreshed := make(chan error)
+	go func() {
+		refreshed <- svc.Check(ctx, "/order.Service/Create")
+	}()
+	<-src.entered
+
+	checked := make(chan error)
+	go func() {
+		checked <- svc.Check(ctx, "/order.Service/Create")
+	}()
+	select {
+	case err := <-checked:
+		if !goerr.IsServiceUnavailableError(err) {
+			t.Errorf("check should use the last state during a refresh, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("check should not wait for the refresh")
+	}
+
+	close(src.release)
+	if err := <-refreshed; !goerr.IsServiceUnavailableError(err) {
+		t.Errorf("refresh should read the state from the source, got %v", err)
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maintenance")
+	svc := NewService(WithSource(NewFileSource(path)), RefreshInterval(0))
+	ctx := context.Background()
+
+	if err := svc.Check(ctx, "/order.Service/Create"); err != nil {
+		t.Fatalf("missing file should allow, got %v", err)
+	}
+
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := svc.Check(ctx, "/order.Service/Create")
+	if !goerr.IsServiceUnavailableError(err) {
+		t.Fatalf("empty file should reject every method, got %v", err)
+	}
+	if err.Error() != DefaultMessage || goerr.GetRetryAfter(err) != DefaultRetryAfter {
+		t.Errorf("defaults should be used, got %q and %v", err.Error(), goerr.GetRetryAfter(err))
+	}
+
+	if err = svc.Set(ctx, &State{}); err != nil {
+		t.Fatalf("set failed %v", err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("disabling should remove the file, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package go_maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRedisKey = "maintenance"
+)
+
+// State is the maintenance switch as kept by a Source.
+type State struct {
+	Enabled bool `json:"enabled"`
+	// Methods limits the maintenance to the methods matching one of the
+	// patterns, every method when empty. A pattern ending with * matches by
+	// prefix, e.g. "/order.Service/*" or "[POST] /v1/orders*" for gin.
+	Methods []string `json:"methods,omitempty"`
+	Message string   `json:"message,omitempty"`
+	// RetryAfterSeconds is sent as retry hint, zero means the default.
+	RetryAfterSeconds int `json:"retry_after_seconds,omitempty"`
+}
+
+func (s *State) retryAfter() time.Duration {
+	return time.Duration(s.RetryAfterSeconds) * time.Second
+}
+
+type Source interface {
+	// Get returns the current state, a disabled state when none is set.
+	Get(ctx context.Context) (*State, error)
+	// Set replaces the state, e.g. from the admin endpoint.
+	Set(ctx context.Context, state *State) error
+}
+
+type localSource struct {
+	mu    sync.RWMutex
+	state State
+}
+
+// NewLocalSource keeps the state in memory, only switched by Service.Set.
+func NewLocalSource() Source {
+	return &localSource{}
+}
+
+func (s *localSource) Get(_ context.Context) (*State, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state := s.state
+	return &state, nil
+}
+
+func (s *localSource) Set(_ context.Context, state *State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = *state
+	return nil
+}
+
+type redisSource struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisSource keeps the state as JSON under key, shared by every instance
+// using the same redis. An empty key means DefaultRedisKey.
+func NewRedisSource(client *redis.Client, key string) Source {
+	if key == "" {
+		key = DefaultRedisKey
+	}
+	return &redisSource{client: client, key: key}
+}
+
+func (s *redisSource) Get(ctx context.Context) (*State, error) {
+	b, err := s.client.Get(ctx, s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return &State{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return decodeState(b)
+}
+
+func (s *redisSource) Set(ctx context.Context, state *State) error {
+	if !state.Enabled {
+		return s.client.Del(ctx, s.key).Err()
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.key, b, 0).Err()
+}
+
+type fileSource struct {
+	path string
+}
+
+// NewFileSource enables maintenance while the file at path exists. The file
+// may hold a JSON State, its enabled flag is ignored. An empty file
+// enables it for every method.
+func NewFileSource(path string) Source {
+	return &fileSource{path: path}
+}
+
+func (s *fileSource) Get(_ context.Context) (*State, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &State{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	state := &State{}
+	if len(b) > 0 {
+		if state, err = decodeState(b); err != nil {
+			return nil, err
+		}
+	}
+	state.Enabled = true
+	return state, nil
+}
+
+func (s *fileSource) Set(_ context.Context, state *State) error {
+	if !state.Enabled {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o644)
+}
+
+func decodeState(b []byte) (*State, error) {
+	state := &State{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -2,6 +2,12 @@ package go_queue
 
 import (
 	"github.com/vmihailenco/taskq/v3"
+	"time"
+)
+
+const (
+	DefaultShutdownTimeout = 30 * time.Second
+	DefaultEnableTracing   = false
 )
 
 type Config struct {
@@ -10,6 +16,9 @@ type Config struct {
 	maxNumWorker  int32
 	workerLimit   int32
 	maxNumFetcher int32
+
+	shutdownTimeout time.Duration
+	enableTracing   bool
 	//redis         taskq.Redis
 }
 
@@ -45,6 +54,25 @@ func MaxNumFetcher(f int32) ConfigFunc {
 	}
 }
 
+// ShutdownTimeout bounds Shutdown when the given context has no deadline.
+func ShutdownTimeout(d time.Duration) ConfigFunc {
+	if d <= 0 {
+		d = DefaultShutdownTimeout
+	}
+	return func(c *Config) {
+		c.shutdownTimeout = d
+	}
+}
+
+// EnableTracing starts a span for every published and processed message.
+// The trace context travels as an extra message arg, so consumers must run a
+// go-queue version that removes it before messages are published with it.
+func EnableTracing(e bool) ConfigFunc {
+	return func(c *Config) {
+		c.enableTracing = e
+	}
+}
+
 //func Redis(r taskq.Redis) ConfigFunc {
 //	return func(c *Config) {
 //		c.redis = r
@@ -63,7 +91,10 @@ func (c *Config) ToQueueOptions() *taskq.QueueOptions {
 }
 
 func generate(args ...ConfigFunc) *Config {
-	c := &Config{}
+	c := &Config{
+		shutdownTimeout: DefaultShutdownTimeout,
+		enableTracing:   DefaultEnableTracing,
+	}
 	for i := range args {
 		args[i](c)
 	}
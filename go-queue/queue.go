@@ -2,10 +2,14 @@ package go_queue
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/vmihailenco/taskq/v3"
 	"os"
 	"os/signal"
 	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	gotrace "pkg.tanyudii.me/go-pkg/go-trace"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -14,8 +18,13 @@ type Worker interface {
 	GetTasks() []*taskq.TaskOptions
 }
 
+// ShutdownHook releases an application resource once the consumer has
+// stopped, e.g. closing a DB or redis client.
+type ShutdownHook func(ctx context.Context) error
+
 type Service interface {
 	Shutdown(ctx context.Context) error
+	RegisterShutdownHook(name string, fn ShutdownHook)
 	RunGracefully(t int)
 	GetQueue() taskq.Queue
 	RegisterWorker(workers ...Worker)
@@ -27,19 +36,69 @@ type Service interface {
 type service struct {
 	cfg   *Config
 	queue taskq.Queue
+
+	mu            sync.Mutex
+	shutdownHooks []shutdownHook
+	shutdownOnce  sync.Once
+	shutdownErr   error
+}
+
+type shutdownHook struct {
+	name string
+	fn   ShutdownHook
 }
 
 func NewService(f taskq.Factory, args ...ConfigFunc) Service {
 	cfg := generate(args...)
+	queue := f.RegisterQueue(cfg.ToQueueOptions())
+	queue.Consumer().AddHook(&tracingHook{queueName: queue.Name(), tracing: cfg.enableTracing})
 	return &service{
 		cfg:   cfg,
-		queue: f.RegisterQueue(cfg.ToQueueOptions()),
+		queue: queue,
 	}
 }
 
+// Shutdown stops the consumer, waiting for in-flight messages until the
+// deadline of ctx, closes the queue and then runs the shutdown hooks in
+// registration order.
 func (s *service) Shutdown(ctx context.Context) error {
-	<-ctx.Done()
-	return nil
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+	})
+	return s.shutdownErr
+}
+
+func (s *service) shutdown(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.shutdownTimeout)
+		defer cancel()
+	}
+
+	var errs []error
+	deadline, _ := ctx.Deadline()
+	if err := s.queue.CloseTimeout(time.Until(deadline)); err != nil {
+		gologger.Errorf("go queue shutdown: failed to close queue %v", err)
+		errs = append(errs, err)
+	}
+
+	s.mu.Lock()
+	hooks := s.shutdownHooks
+	s.mu.Unlock()
+	for _, hook := range hooks {
+		if err := hook.fn(ctx); err != nil {
+			gologger.Errorf("go queue shutdown: hook %s failed %v", hook.name, err)
+			errs = append(errs, fmt.Errorf("shutdown hook %s: %w", hook.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *service) RegisterShutdownHook(name string, fn ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, shutdownHook{name: name, fn: fn})
 }
 
 func (s *service) RunGracefully(t int) {
@@ -56,7 +115,7 @@ func (s *service) RunGracefully(t int) {
 	gologger.Infof("go queue is shutting down: for %ds %v", t, time.Now())
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(t)*time.Second)
 	defer cancel()
-	cancelMainCtx()
+	defer cancelMainCtx()
 	if err := s.Shutdown(ctx); err != nil {
 		gologger.Fatalf("go queue shutdown err: %v", err)
 	}
@@ -88,9 +147,15 @@ func (s *service) AddMessageWithSchedule(ctx context.Context, taskName string, s
 	if schedule != nil && schedule.After(now) {
 		msg.SetDelay(schedule.Sub(now))
 	}
-	return s.queue.Add(msg)
+	return s.AddMessageRaw(msg)
 }
 
 func (s *service) AddMessageRaw(msg *taskq.Message) error {
-	return s.queue.Add(msg)
+	if !s.cfg.enableTracing {
+		return s.queue.Add(msg)
+	}
+	span := s.startPublishSpan(msg)
+	err := s.queue.Add(msg)
+	gotrace.End(span, err)
+	return err
 }
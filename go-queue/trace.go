@@ -0,0 +1,151 @@
+package go_queue
+
+import (
+	"bytes"
+	"context"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/taskq/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	gotrace "pkg.tanyudii.me/go-pkg/go-trace"
+)
+
+const (
+	messagingSystemTaskq = "taskq"
+)
+
+type tracingSpanKey struct{}
+
+// traceCarrier is appended to the args of a published message, as taskq
+// messages carry no headers, and removed again before the handler runs.
+type traceCarrier struct {
+	TraceContext map[string]string `msgpack:"__goqueue_trace_context"`
+}
+
+// tracingHook removes the trace carrier of every processed message and,
+// with tracing enabled, starts a consumer span around it as a child of the
+// publishing span.
+type tracingHook struct {
+	queueName string
+	tracing   bool
+}
+
+func (h *tracingHook) BeforeProcessMessage(evt *taskq.ProcessMessageEvent) error {
+	msg := evt.Message
+	ctx := msg.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	carrier := takeTraceCarrier(msg)
+	if !h.tracing {
+		return nil
+	}
+	ctx = gotrace.Propagator().Extract(ctx, propagation.MapCarrier(carrier))
+	ctx, span := gotrace.Start(
+		ctx,
+		"taskq.process "+msg.TaskName,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(messagingAttributes(h.queueName, msg)...),
+	)
+	msg.Ctx = ctx
+	if evt.Stash == nil {
+		evt.Stash = make(map[interface{}]interface{})
+	}
+	evt.Stash[tracingSpanKey{}] = span
+	return nil
+}
+
+func (h *tracingHook) AfterProcessMessage(evt *taskq.ProcessMessageEvent) error {
+	if span, ok := evt.Stash[tracingSpanKey{}].(trace.Span); ok {
+		gotrace.End(span, evt.Message.Err)
+	}
+	return nil
+}
+
+func (s *service) startPublishSpan(msg *taskq.Message) trace.Span {
+	ctx := msg.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := gotrace.Start(
+		ctx,
+		"taskq.publish "+msg.TaskName,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(append(
+			messagingAttributes(s.queue.Name(), msg),
+			semconv.MessagingOperationPublish,
+		)...),
+	)
+	msg.Ctx = ctx
+
+	// args set as binary are sent as they are
+	if msg.ArgsBin == nil {
+		carrier := propagation.MapCarrier{}
+		gotrace.Propagator().Inject(ctx, carrier)
+		if len(carrier) > 0 {
+			n := len(msg.Args)
+			msg.Args = append(msg.Args[:n:n], traceCarrier{TraceContext: carrier})
+		}
+	}
+	return span
+}
+
+// takeTraceCarrier removes the trace carrier from the args of msg, either
+// still in memory or decoded from the queue, and returns its trace context.
+func takeTraceCarrier(msg *taskq.Message) map[string]string {
+	if n := len(msg.Args); n > 0 {
+		carrier, ok := msg.Args[n-1].(traceCarrier)
+		if !ok {
+			return nil
+		}
+		msg.Args = msg.Args[:n-1]
+		msg.ArgsBin = nil
+		msg.ArgsCompression = ""
+		return carrier.TraceContext
+	}
+	if msg.ArgsBin == nil || msg.ArgsCompression != "" {
+		return nil
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(msg.ArgsBin))
+	n, err := dec.DecodeArrayLen()
+	if err != nil || n < 1 {
+		return nil
+	}
+	args := make([]msgpack.RawMessage, n)
+	for i := range args {
+		if args[i], err = dec.DecodeRaw(); err != nil {
+			return nil
+		}
+	}
+	var carrier traceCarrier
+	if err = msgpack.Unmarshal(args[n-1], &carrier); err != nil || carrier.TraceContext == nil {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	enc := msgpack.NewEncoder(buf)
+	if err = enc.EncodeArrayLen(n - 1); err != nil {
+		return nil
+	}
+	for _, arg := range args[:n-1] {
+		if err = enc.Encode(arg); err != nil {
+			return nil
+		}
+	}
+	msg.ArgsBin = buf.Bytes()
+	return carrier.TraceContext
+}
+
+func messagingAttributes(queueName string, msg *taskq.Message) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String(messagingSystemTaskq),
+		semconv.MessagingDestinationName(queueName),
+	}
+	if msg.ID != "" {
+		attrs = append(attrs, semconv.MessagingMessageID(msg.ID))
+	}
+	return attrs
+}
@@ -0,0 +1,94 @@
+package go_queue
+
+import (
+	"context"
+	"github.com/vmihailenco/taskq/v3"
+	"github.com/vmihailenco/taskq/v3/memqueue"
+	"io"
+	gotrace "pkg.tanyudii.me/go-pkg/go-trace"
+	"testing"
+)
+
+func TestTracePropagation(t *testing.T) {
+	exp, err := gotrace.NewWriterExporter(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer, err := gotrace.NewService(gotrace.WithExporter(exp), gotrace.SyncExport(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tracer.Shutdown(context.Background()) }()
+
+	svc := NewService(memqueue.NewFactory(), Name("trace-test"), EnableTracing(true))
+	svc.GetQueue().(*memqueue.Queue).SetSync(true)
+	defer func() { _ = svc.GetQueue().Close() }()
+
+	var gotArg, gotTraceID string
+	taskq.RegisterTask(&taskq.TaskOptions{
+		Name: "trace-test-task",
+		Handler: func(ctx context.Context, arg string) error {
+			gotArg = arg
+			gotTraceID = gotrace.TraceID(ctx)
+			return nil
+		},
+	})
+
+	ctx, span := gotrace.Start(context.Background(), "parent")
+	if err = svc.AddMessage(ctx, "trace-test-task", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+
+	if gotArg != "hello" {
+		t.Errorf("handler should get its own args, got %q", gotArg)
+	}
+	if gotTraceID == "" || gotTraceID != gotrace.TraceID(ctx) {
+		t.Errorf("consume span should continue trace %s, got %q", gotrace.TraceID(ctx), gotTraceID)
+	}
+}
+
+func TestTakeTraceCarrierFromQueue(t *testing.T) {
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	msg := taskq.NewMessage(context.Background(), "hello", 2, traceCarrier{
+		TraceContext: map[string]string{"traceparent": traceParent},
+	})
+	msg.TaskName = "trace-test-task"
+	b, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// as read back by a redis consumer
+	consumed := &taskq.Message{}
+	if err = consumed.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if carrier := takeTraceCarrier(consumed); carrier["traceparent"] != traceParent {
+		t.Errorf("trace context should be read from the message, got %v", carrier)
+	}
+
+	var gotArg string
+	var gotN int
+	handler := taskq.NewHandler(func(arg string, n int) error {
+		gotArg, gotN = arg, n
+		return nil
+	})
+	if err = handler.HandleMessage(consumed); err != nil {
+		t.Fatalf("handler should get the args without the carrier: %v", err)
+	}
+	if gotArg != "hello" || gotN != 2 {
+		t.Errorf("unexpected args %q %d", gotArg, gotN)
+	}
+
+	plain := taskq.NewMessage(context.Background(), "hello")
+	plain.TaskName = "trace-test-task"
+	if b, err = plain.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	consumed = &taskq.Message{}
+	_ = consumed.UnmarshalBinary(b)
+	if carrier := takeTraceCarrier(consumed); carrier != nil {
+		t.Errorf("messages without a carrier should be left alone, got %v", carrier)
+	}
+}
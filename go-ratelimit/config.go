@@ -0,0 +1,122 @@
+package go_ratelimit
+
+import (
+	"fmt"
+)
+
+type KeyBy string
+
+const (
+	KeyByMethod   KeyBy = "method"
+	KeyByUser     KeyBy = "user"
+	KeyByClientID KeyBy = "client"
+	KeyByIP       KeyBy = "ip"
+)
+
+type MapMethodLimits map[string]Limit
+
+type Config struct {
+	limiter      Limiter
+	keyBy        KeyBy
+	defaultLimit *Limit
+	methodLimits MapMethodLimits
+	skipMethods  map[string]bool
+	failOpen     bool
+
+	trustedProxies int
+}
+
+type ConfigFunc func(c *Config)
+
+func WithLimiter(l Limiter) ConfigFunc {
+	return func(c *Config) {
+		c.limiter = l
+	}
+}
+
+// WithKeyBy decides who shares a bucket within a method. User and client
+// keys fall back to the IP when the caller is anonymous. They are read from
+// gotex, so the interceptor must run after the auth interceptor, otherwise
+// the userid and clientid headers claimed by the client are used.
+func WithKeyBy(k KeyBy) ConfigFunc {
+	return func(c *Config) {
+		c.keyBy = k
+	}
+}
+
+// DefaultLimit applies to every method without its own limit. Without it,
+// only methods in MethodLimits are limited.
+func DefaultLimit(l Limit) ConfigFunc {
+	return func(c *Config) {
+		c.defaultLimit = &l
+	}
+}
+
+func MethodLimits(m MapMethodLimits) ConfigFunc {
+	return func(c *Config) {
+		for method, l := range m {
+			c.methodLimits[method] = l
+		}
+	}
+}
+
+func SkipMethods(methods ...string) ConfigFunc {
+	return func(c *Config) {
+		for _, m := range methods {
+			c.skipMethods[m] = true
+		}
+	}
+}
+
+// FailOpen lets requests through when the limiter itself errors, e.g. when
+// redis is unreachable.
+func FailOpen(f bool) ConfigFunc {
+	return func(c *Config) {
+		c.failOpen = f
+	}
+}
+
+// TrustedProxies is the number of proxies, e.g. load balancers, in front of
+// the REST gateway whose X-Forwarded-For hops are trusted. The default 0
+// only trusts the hop the gateway appends, which is the address it was
+// called from.
+func TrustedProxies(n int) ConfigFunc {
+	return func(c *Config) {
+		c.trustedProxies = n
+	}
+}
+
+func generate(args ...ConfigFunc) *Config {
+	c := &Config{
+		keyBy:        KeyByMethod,
+		methodLimits: make(MapMethodLimits),
+		skipMethods:  make(map[string]bool),
+		failOpen:     true,
+	}
+	for i := range args {
+		args[i](c)
+	}
+	if c.limiter == nil {
+		c.limiter = NewLocalLimiter()
+	}
+
+	// never limit grpc health check
+	c.skipMethods["/grpc.health.v1.Health/Check"] = true
+	c.skipMethods["/grpc.health.v1.Health/Watch"] = true
+
+	return c
+}
+
+func (c *Config) validate() error {
+	if c.defaultLimit != nil {
+		if err := c.defaultLimit.validate(); err != nil {
+			return fmt.Errorf("default limit: %w", err)
+		}
+	}
+	for method, l := range c.methodLimits {
+		if err := l.validate(); err != nil {
+			return fmt.Errorf("limit of %s: %w", method, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package gin_ratelimit
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	goratelimit "pkg.tanyudii.me/go-pkg/go-ratelimit"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strconv"
+)
+
+// RateLimit limits requests by "[METHOD] /full/path". Register it after the
+// auth middleware when limiting by user or client.
+func RateLimit(svc goratelimit.Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		method := fmt.Sprintf("[%s] %s", c.Request.Method, c.FullPath())
+		if err := svc.Allow(c.Request.Context(), method, identityFromContext(c)); err != nil {
+			if retryAfter := goerr.GetRetryAfter(err); retryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			}
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func identityFromContext(c *gin.Context) *goratelimit.Identity {
+	identity := &goratelimit.Identity{IP: c.ClientIP()}
+	if gtx, ok := gotex.FromContext(c.Request.Context()); ok {
+		identity.UserID = gtx.UserID
+		identity.ClientID = gtx.ClientID
+	}
+	return identity
+}
@@ -0,0 +1,58 @@
+package grpc_ratelimit
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"net"
+	goratelimit "pkg.tanyudii.me/go-pkg/go-ratelimit"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+)
+
+// UnaryInterceptor limits unary calls. Register it after the auth
+// interceptor when limiting by user or client.
+func UnaryInterceptor(svc goratelimit.Service) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := svc.Allow(ctx, info.FullMethod, identityFromContext(ctx)); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamInterceptor(svc goratelimit.Service) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := svc.Allow(ss.Context(), info.FullMethod, identityFromContext(ss.Context())); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func identityFromContext(ctx context.Context) *goratelimit.Identity {
+	md := gotex.FromIncoming(ctx)
+	gtx, ok := gotex.FromContext(ctx)
+	if !ok {
+		gtx = gotex.NewGotex(md)
+	}
+	identity := &goratelimit.Identity{
+		UserID:   gtx.UserID,
+		ClientID: gtx.ClientID,
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		identity.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(identity.IP); err == nil {
+			identity.IP = host
+		}
+	}
+	// only the REST gateway of the same process relays X-Forwarded-For,
+	// direct gRPC callers could send any
+	if fwd := md.Get(strings.ToLower(gotex.RequestHeaderKeyXForwardedFor)); fwd != "" && gotex.FromGateway(ctx) {
+		for _, hop := range strings.Split(fwd, ",") {
+			identity.ForwardedFor = append(identity.ForwardedFor, strings.TrimSpace(hop))
+		}
+	}
+	return identity
+}
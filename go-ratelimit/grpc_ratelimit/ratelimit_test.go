@@ -0,0 +1,26 @@
+package grpc_ratelimit
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"testing"
+)
+
+func TestIdentityFromContextForwardedFor(t *testing.T) {
+	loopback := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}})
+	incoming := func(ctx context.Context) context.Context {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		md = metadata.Join(md, metadata.Pairs("x-forwarded-for", "203.0.113.7"))
+		return metadata.NewIncomingContext(loopback, md)
+	}
+
+	if identity := identityFromContext(incoming(context.Background())); identity.IP != "127.0.0.1" || len(identity.ForwardedFor) != 0 {
+		t.Errorf("X-Forwarded-For of a local caller other than the gateway should be ignored, got %+v", identity)
+	}
+	if identity := identityFromContext(incoming(gotex.WithGatewayMarker(context.Background()))); len(identity.ForwardedFor) != 1 || identity.ForwardedFor[0] != "203.0.113.7" {
+		t.Errorf("X-Forwarded-For relayed by the gateway should be trusted, got %+v", identity)
+	}
+}
@@ -0,0 +1,155 @@
+package go_ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redis_rate/v9"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidLimit = errors.New("[ERROR]: Invalid rate limit")
+)
+
+type Limit struct {
+	Rate   int
+	Burst  int
+	Period time.Duration
+}
+
+func PerSecond(rate int) Limit {
+	return Limit{Rate: rate, Burst: rate, Period: time.Second}
+}
+
+func PerMinute(rate int) Limit {
+	return Limit{Rate: rate, Burst: rate, Period: time.Minute}
+}
+
+func PerHour(rate int) Limit {
+	return Limit{Rate: rate, Burst: rate, Period: time.Hour}
+}
+
+func (l Limit) validate() error {
+	if l.Rate <= 0 || l.Period <= 0 || l.Burst < 0 {
+		return fmt.Errorf("%w: rate %d, burst %d per %v", ErrInvalidLimit, l.Rate, l.Burst, l.Period)
+	}
+	return nil
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+// localLimiter is an in-process token bucket per key, for single instances.
+type localLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	sweptAt   time.Time
+	sweepEach time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+func NewLocalLimiter() Limiter {
+	return &localLimiter{
+		buckets:   make(map[string]*bucket),
+		sweptAt:   time.Now(),
+		sweepEach: time.Minute,
+	}
+}
+
+func (l *localLimiter) Allow(_ context.Context, key string, limit Limit) (*Result, error) {
+	if err := limit.validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	perSecond := float64(limit.Rate) / limit.Period.Seconds()
+	burst := float64(limit.burst())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.limit = limit
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		return &Result{
+			Allowed:    false,
+			RetryAfter: time.Duration((1 - b.tokens) / perSecond * float64(time.Second)),
+		}, nil
+	}
+	b.tokens--
+	return &Result{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// sweep drops buckets that have refilled completely, they behave the same as
+// a missing bucket.
+func (l *localLimiter) sweep(now time.Time) {
+	if now.Sub(l.sweptAt) < l.sweepEach {
+		return
+	}
+	l.sweptAt = now
+	for key, b := range l.buckets {
+		perSecond := float64(b.limit.Rate) / b.limit.Period.Seconds()
+		if b.tokens+now.Sub(b.last).Seconds()*perSecond >= float64(b.limit.burst()) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// redisLimiter is a GCRA limiter shared by every instance using the same
+// redis, e.g. a client from connection/redis.
+type redisLimiter struct {
+	limiter *redis_rate.Limiter
+}
+
+func NewRedisLimiter(cli *redis.Client) Limiter {
+	return &redisLimiter{limiter: redis_rate.NewLimiter(cli)}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	if err := limit.validate(); err != nil {
+		return nil, err
+	}
+	res, err := l.limiter.Allow(ctx, key, redis_rate.Limit{
+		Rate:   limit.Rate,
+		Burst:  limit.burst(),
+		Period: limit.Period,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Allowed:    res.Allowed > 0,
+		Remaining:  res.Remaining,
+		RetryAfter: res.RetryAfter,
+	}, nil
+}
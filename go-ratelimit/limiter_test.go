@@ -0,0 +1,92 @@
+package go_ratelimit
+
+import (
+	"context"
+	"errors"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	"testing"
+	"time"
+)
+
+func TestServiceAllowLocalLimiter(t *testing.T) {
+	svc := newService(t,
+		WithKeyBy(KeyByUser),
+		MethodLimits(MapMethodLimits{
+			"/order.Service/Create": {Rate: 2, Period: time.Minute},
+		}),
+	)
+	ctx := context.Background()
+	alice := &Identity{UserID: "alice", IP: "10.0.0.1"}
+	bob := &Identity{UserID: "bob", IP: "10.0.0.1"}
+
+	for i := 0; i < 2; i++ {
+		if err := svc.Allow(ctx, "/order.Service/Create", alice); err != nil {
+			t.Fatalf("request %d should be allowed, got %v", i, err)
+		}
+	}
+
+	err := svc.Allow(ctx, "/order.Service/Create", alice)
+	if !goerr.IsTooManyRequestError(err) {
+		t.Fatalf("error should be TooManyRequestError, got %v", err)
+	}
+	if retryAfter := goerr.GetRetryAfter(err); retryAfter <= 0 || retryAfter > 30*time.Second {
+		t.Errorf("retry after should be within (0, 30s], got %v", retryAfter)
+	}
+
+	if err = svc.Allow(ctx, "/order.Service/Create", bob); err != nil {
+		t.Errorf("other user should have its own bucket, got %v", err)
+	}
+	if err = svc.Allow(ctx, "/order.Service/List", alice); err != nil {
+		t.Errorf("method without limit should be allowed, got %v", err)
+	}
+}
+
+func TestServiceClientIP(t *testing.T) {
+	limits := MethodLimits(MapMethodLimits{"/order.Service/Create": {Rate: 1, Period: time.Minute}})
+	ctx := context.Background()
+	spoofed := func(spoof string) *Identity {
+		return &Identity{IP: "127.0.0.1", ForwardedFor: []string{spoof, "203.0.113.7"}}
+	}
+
+	svc := newService(t, WithKeyBy(KeyByIP), limits)
+	if err := svc.Allow(ctx, "/order.Service/Create", spoofed("10.0.0.1")); err != nil {
+		t.Fatalf("first request should be allowed, got %v", err)
+	}
+	if err := svc.Allow(ctx, "/order.Service/Create", spoofed("10.0.0.2")); !goerr.IsTooManyRequestError(err) {
+		t.Errorf("rotating the client set hops should not escape the limit, got %v", err)
+	}
+
+	svc = newService(t, WithKeyBy(KeyByIP), TrustedProxies(1), limits)
+	if err := svc.Allow(ctx, "/order.Service/Create", spoofed("10.0.0.1")); err != nil {
+		t.Fatalf("first request should be allowed, got %v", err)
+	}
+	if err := svc.Allow(ctx, "/order.Service/Create", spoofed("10.0.0.2")); err != nil {
+		t.Errorf("hop of a trusted proxy should be the client, got %v", err)
+	}
+}
+
+func TestNewServiceInvalidLimit(t *testing.T) {
+	_, err := NewService(MethodLimits(MapMethodLimits{"/order.Service/Create": {Rate: 1}}))
+	if !errors.Is(err, ErrInvalidLimit) {
+		t.Errorf("method limit without period should fail the service, got %v", err)
+	}
+	if _, err = NewService(DefaultLimit(Limit{Period: time.Second})); !errors.Is(err, ErrInvalidLimit) {
+		t.Errorf("default limit without rate should fail the service, got %v", err)
+	}
+}
+
+func TestLocalLimiterInvalidLimit(t *testing.T) {
+	_, err := NewLocalLimiter().Allow(context.Background(), "key", Limit{Rate: 1})
+	if !errors.Is(err, ErrInvalidLimit) {
+		t.Errorf("limit without period should be invalid, got %v", err)
+	}
+}
+
+func newService(t *testing.T, args ...ConfigFunc) Service {
+	t.Helper()
+	svc, err := NewService(args...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return svc
+}
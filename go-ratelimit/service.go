@@ -0,0 +1,108 @@
+package go_ratelimit
+
+import (
+	"context"
+	"fmt"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	gologger "pkg.tanyudii.me/go-pkg/go-logger"
+	"time"
+)
+
+const (
+	ErrNameTooManyRequest = "TOO_MANY_REQUEST"
+	keyPrefix             = "ratelimit"
+)
+
+// Identity is the caller of a request as seen by the transport.
+type Identity struct {
+	UserID   string
+	ClientID string
+	// IP is the address of the peer.
+	IP string
+	// ForwardedFor are the X-Forwarded-For hops of a request relayed by a
+	// trusted proxy, e.g. the REST gateway, see TrustedProxies.
+	ForwardedFor []string
+}
+
+type Service interface {
+	Allow(ctx context.Context, method string, identity *Identity) error
+}
+
+type service struct {
+	cfg *Config
+}
+
+// NewService fails on invalid limits, instead of every call to the
+// limited methods failing.
+func NewService(args ...ConfigFunc) (Service, error) {
+	cfg := generate(args...)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &service{
+		cfg: cfg,
+	}, nil
+}
+
+func (s *service) Allow(ctx context.Context, method string, identity *Identity) error {
+	if s.cfg.skipMethods[method] {
+		return nil
+	}
+	limit, ok := s.cfg.methodLimits[method]
+	if !ok {
+		if s.cfg.defaultLimit == nil {
+			return nil
+		}
+		limit = *s.cfg.defaultLimit
+	}
+
+	res, err := s.cfg.limiter.Allow(ctx, s.key(method, identity), limit)
+	if err != nil {
+		if s.cfg.failOpen {
+			gologger.Errorf("go ratelimit: limiter failed, allowing request %v", err)
+			return nil
+		}
+		return err
+	}
+	if !res.Allowed {
+		return goerr.NewTooManyRequestErrorWithNameAndRetryAfter(
+			fmt.Sprintf("too many requests, retry after %v", res.RetryAfter.Round(time.Millisecond)),
+			ErrNameTooManyRequest,
+			res.RetryAfter,
+		)
+	}
+	return nil
+}
+
+func (s *service) key(method string, identity *Identity) string {
+	if identity == nil {
+		identity = &Identity{}
+	}
+	switch s.cfg.keyBy {
+	case KeyByUser:
+		if identity.UserID != "" {
+			return fmt.Sprintf("%s:%s:user:%s", keyPrefix, method, identity.UserID)
+		}
+	case KeyByClientID:
+		if identity.ClientID != "" {
+			return fmt.Sprintf("%s:%s:client:%s", keyPrefix, method, identity.ClientID)
+		}
+	case KeyByMethod:
+		return fmt.Sprintf("%s:%s", keyPrefix, method)
+	}
+	return fmt.Sprintf("%s:%s:ip:%s", keyPrefix, method, s.clientIP(identity))
+}
+
+// clientIP skips the hops appended by the trusted proxies from the right of
+// X-Forwarded-For, the hops left of them are set by the client at will.
+func (s *service) clientIP(identity *Identity) string {
+	hops := identity.ForwardedFor
+	if len(hops) == 0 {
+		return identity.IP
+	}
+	i := len(hops) - 1 - s.cfg.trustedProxies
+	if i < 0 {
+		i = 0
+	}
+	return hops[i]
+}
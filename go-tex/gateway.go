@@ -0,0 +1,41 @@
+package go_tex
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"google.golang.org/grpc/metadata"
+)
+
+// gatewayHeaderKey is not a Gotex field, so it is never propagated to other
+// services, and not forwarded from REST requests by the gateway.
+const gatewayHeaderKey = "x-gotex-gateway"
+
+// gatewayToken is only known to this process, a caller can not claim to be
+// its REST gateway without it.
+var gatewayToken = newGatewayToken()
+
+func newGatewayToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithGatewayMarker marks the outgoing call as relayed by the REST gateway
+// of this process, see FromGateway.
+func WithGatewayMarker(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(gatewayHeaderKey, gatewayToken)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// FromGateway reports whether the incoming call was relayed by the REST
+// gateway of this process, e.g. to trust the X-Forwarded-For it appends.
+func FromGateway(ctx context.Context) bool {
+	v := FromIncoming(ctx).Get(gatewayHeaderKey)
+	return v != "" && subtle.ConstantTimeCompare([]byte(v), []byte(gatewayToken)) == 1
+}
@@ -33,9 +33,14 @@ const (
 	RequestHeaderKeyAcceptLanguage       = "Accept-Language"
 	RequestHeaderKeyXForwardedFor        = "X-Forwarded-For"
 	RequestHeaderUserAgent               = "User-Agent"
+	RequestHeaderKeyPeerCommonName       = "PeerCommonName"
+	RequestHeaderKeyPeerSANs             = "PeerSANs"
+	RequestHeaderKeyTraceParent          = "traceparent"
+	RequestHeaderKeyTraceState           = "tracestate"
 
 	ScopeSeparator      = " "
 	PermissionSeparator = ";"
+	SANSeparator        = ","
 )
 
 type Gotex struct {
@@ -55,6 +60,16 @@ type Gotex struct {
 	AcceptLanguage       string
 	XForwardedFor        string
 	UserAgent            string
+
+	// PeerCommonName and PeerSANs hold the identity of a verified mTLS client
+	// certificate. They are set by the server per hop and never propagated.
+	PeerCommonName string
+	PeerSANs       string
+
+	// TraceParent and TraceState are the W3C trace context of the current
+	// span, kept in sync by the go-grpc tracing interceptors.
+	TraceParent string
+	TraceState  string
 }
 
 func NewGotex(md ContextMD) *Gotex {
@@ -75,6 +90,10 @@ func NewGotex(md ContextMD) *Gotex {
 		AcceptLanguage:       md.Get(strings.ToLower(RequestHeaderKeyAcceptLanguage)),
 		XForwardedFor:        md.Get(strings.ToLower(RequestHeaderKeyXForwardedFor)),
 		UserAgent:            md.Get(strings.ToLower(RequestHeaderUserAgent)),
+		PeerCommonName:       md.Get(strings.ToLower(RequestHeaderKeyPeerCommonName)),
+		PeerSANs:             md.Get(strings.ToLower(RequestHeaderKeyPeerSANs)),
+		TraceParent:          md.Get(strings.ToLower(RequestHeaderKeyTraceParent)),
+		TraceState:           md.Get(strings.ToLower(RequestHeaderKeyTraceState)),
 	}
 }
 
@@ -96,6 +115,10 @@ func (c *Gotex) ToContextMD(ctx context.Context) context.Context {
 	md.Set(strings.ToLower(RequestHeaderKeyAcceptLanguage), c.AcceptLanguage)
 	md.Set(strings.ToLower(RequestHeaderKeyXForwardedFor), c.XForwardedFor)
 	md.Set(strings.ToLower(RequestHeaderUserAgent), c.UserAgent)
+	md.Set(strings.ToLower(RequestHeaderKeyPeerCommonName), c.PeerCommonName)
+	md.Set(strings.ToLower(RequestHeaderKeyPeerSANs), c.PeerSANs)
+	md.Set(strings.ToLower(RequestHeaderKeyTraceParent), c.TraceParent)
+	md.Set(strings.ToLower(RequestHeaderKeyTraceState), c.TraceState)
 	ctx = NewContext(ctx, c)
 	return md.ToIncoming(ctx)
 }
@@ -154,6 +177,22 @@ func (c *Gotex) HasUserTypeByMapCode(codes map[string]bool) (bool, error) {
 	return false, ErrUnauthorizedUserType
 }
 
+func (c *Gotex) HasPeerIdentity(identities []string) bool {
+	if len(identities) == 0 {
+		return false
+	}
+	mapIdentity := sliceStringsToMap(identities)
+	if c.PeerCommonName != "" && mapIdentity[c.PeerCommonName] {
+		return true
+	}
+	for _, san := range splitString(c.PeerSANs, SANSeparator) {
+		if mapIdentity[san] {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Gotex) ToRequestHeaders() map[string]string {
 	return map[string]string{
 		RequestHeaderKeyUserID:               c.UserID,
@@ -172,6 +211,8 @@ func (c *Gotex) ToRequestHeaders() map[string]string {
 		RequestHeaderKeyAcceptLanguage:       c.AcceptLanguage,
 		RequestHeaderKeyXForwardedFor:        c.XForwardedFor,
 		RequestHeaderUserAgent:               c.UserAgent,
+		RequestHeaderKeyTraceParent:          c.TraceParent,
+		RequestHeaderKeyTraceState:           c.TraceState,
 	}
 }
 
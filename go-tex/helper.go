@@ -3,6 +3,7 @@ package go_tex
 import (
 	"context"
 	"strings"
+	"time"
 )
 
 func GetUserID(ctx context.Context) (string, error) {
@@ -40,6 +41,17 @@ func GetAcceptLanguage(ctx context.Context, defaultVal ...string) string {
 	return acceptLang
 }
 
+// GetRemainingBudget returns the time left before the deadline of ctx. It
+// is false when ctx has no deadline. Pass ctx on, e.g. db.WithContext(ctx),
+// so queries and outbound calls stop with the request.
+func GetRemainingBudget(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
 func DuplicateCtx(ctx context.Context) (context.Context, error) {
 	eCtx, err := FromContextWithErr(ctx)
 	if err != nil {
@@ -0,0 +1,51 @@
+package go_tex
+
+import (
+	"context"
+	"sync"
+)
+
+type verifiedIdentityKey struct{}
+
+type verifiedIdentity struct {
+	mu  sync.RWMutex
+	gtx *Gotex
+}
+
+// WithVerifiedIdentity makes ctx collect the identity auth verifies further
+// down the call chain, read back by VerifiedIdentity once the call returned,
+// e.g. by an access log.
+func WithVerifiedIdentity(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(verifiedIdentityKey{}).(*verifiedIdentity); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, verifiedIdentityKey{}, &verifiedIdentity{})
+}
+
+// SetVerifiedIdentity records gtx as the identity verified by auth, for the
+// callers up the chain that called WithVerifiedIdentity and those down the
+// chain of the returned ctx.
+func SetVerifiedIdentity(ctx context.Context, gtx *Gotex) context.Context {
+	v, ok := ctx.Value(verifiedIdentityKey{}).(*verifiedIdentity)
+	if !ok {
+		v = &verifiedIdentity{}
+		ctx = context.WithValue(ctx, verifiedIdentityKey{}, v)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.gtx = gtx
+	return ctx
+}
+
+// VerifiedIdentity returns the identity verified by auth, nil when the call
+// was not authenticated. Unlike FromContext it never holds identity headers
+// claimed by the client.
+func VerifiedIdentity(ctx context.Context) *Gotex {
+	v, ok := ctx.Value(verifiedIdentityKey{}).(*verifiedIdentity)
+	if !ok {
+		return nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.gtx
+}
@@ -0,0 +1,58 @@
+package go_trace
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	DefaultSampleRatio = 1
+)
+
+type Config struct {
+	serviceName string
+	exporters   []sdktrace.SpanExporter
+	sampleRatio float64
+	syncExport  bool
+}
+
+type ConfigFunc func(c *Config)
+
+func ServiceName(n string) ConfigFunc {
+	return func(c *Config) {
+		c.serviceName = n
+	}
+}
+
+// WithExporter adds an exporter spans are sent to, e.g. an OTLP exporter or
+// one of the writer exporters of this package.
+func WithExporter(e ...sdktrace.SpanExporter) ConfigFunc {
+	return func(c *Config) {
+		c.exporters = append(c.exporters, e...)
+	}
+}
+
+// SampleRatio is the fraction of new traces that are recorded. Traces
+// started upstream keep the sampling decision of their parent.
+func SampleRatio(r float64) ConfigFunc {
+	return func(c *Config) {
+		c.sampleRatio = r
+	}
+}
+
+// SyncExport exports every span as soon as it ends instead of in batches.
+// Meant for tests and debugging.
+func SyncExport(s bool) ConfigFunc {
+	return func(c *Config) {
+		c.syncExport = s
+	}
+}
+
+func generate(args ...ConfigFunc) *Config {
+	c := &Config{
+		sampleRatio: DefaultSampleRatio,
+	}
+	for i := range args {
+		args[i](c)
+	}
+	return c
+}
@@ -0,0 +1,46 @@
+package go_trace
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"io"
+	"os"
+)
+
+// NewStdoutExporter writes finished spans to stdout as JSON.
+func NewStdoutExporter() (sdktrace.SpanExporter, error) {
+	return NewWriterExporter(os.Stdout)
+}
+
+// NewWriterExporter writes finished spans to w as JSON, one span per line.
+func NewWriterExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+// NewFileExporter appends finished spans to the file at path as JSON, one
+// span per line. The file is closed when the exporter is shut down.
+func NewFileExporter(path string) (sdktrace.SpanExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := NewWriterExporter(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &fileExporter{SpanExporter: exp, file: f}, nil
+}
+
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	if err := e.SpanExporter.Shutdown(ctx); err != nil {
+		return err
+	}
+	return e.file.Close()
+}
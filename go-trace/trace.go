@@ -0,0 +1,135 @@
+package go_trace
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+)
+
+const (
+	TracerName = "pkg.tanyudii.me/go-pkg"
+)
+
+var (
+	propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+)
+
+type Service interface {
+	Shutdown(ctx context.Context) error
+	ForceFlush(ctx context.Context) error
+	TracerProvider() trace.TracerProvider
+}
+
+type service struct {
+	cfg      *Config
+	provider *sdktrace.TracerProvider
+}
+
+// NewService installs a tracer provider exporting to the configured
+// exporters as the global one. Without a service, spans are still created
+// and trace context is still propagated, but nothing is recorded.
+func NewService(args ...ConfigFunc) (Service, error) {
+	cfg := generate(args...)
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.sampleRatio))),
+	}
+	for _, e := range cfg.exporters {
+		if cfg.syncExport {
+			opts = append(opts, sdktrace.WithSyncer(e))
+		} else {
+			opts = append(opts, sdktrace.WithBatcher(e))
+		}
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return &service{cfg: cfg, provider: provider}, nil
+}
+
+// Shutdown flushes pending spans and stops the exporters. It has the
+// signature of a go-grpc or go-queue shutdown hook.
+func (s *service) Shutdown(ctx context.Context) error {
+	return s.provider.Shutdown(ctx)
+}
+
+func (s *service) ForceFlush(ctx context.Context) error {
+	return s.provider.ForceFlush(ctx)
+}
+
+func (s *service) TracerProvider() trace.TracerProvider {
+	return s.provider
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+func Propagator() propagation.TextMapPropagator {
+	return propagator
+}
+
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Extract returns ctx carrying the remote span context found in md, e.g.
+// the traceparent sent by the caller.
+func Extract(ctx context.Context, md gotex.ContextMD) context.Context {
+	return propagator.Extract(ctx, mdCarrier(md))
+}
+
+// Inject writes the span context of ctx into md.
+func Inject(ctx context.Context, md gotex.ContextMD) {
+	propagator.Inject(ctx, mdCarrier(md))
+}
+
+// TraceID returns the trace id of the span in ctx, or "" without one.
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+var _ propagation.TextMapCarrier = mdCarrier(nil)
+
+type mdCarrier gotex.ContextMD
+
+func (c mdCarrier) Get(key string) string {
+	return gotex.ContextMD(c).Get(strings.ToLower(key))
+}
+
+func (c mdCarrier) Set(key string, value string) {
+	gotex.ContextMD(c).Set(strings.ToLower(key), value)
+}
+
+func (c mdCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,60 @@
+package go_trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	gotex "pkg.tanyudii.me/go-pkg/go-tex"
+	"strings"
+	"testing"
+)
+
+func TestWriterExporterPropagation(t *testing.T) {
+	buf := &bytes.Buffer{}
+	exp, err := NewWriterExporter(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svc, err := NewService(ServiceName("order"), WithExporter(exp), SyncExport(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = svc.Shutdown(context.Background()) }()
+
+	ctx, parent := Start(context.Background(), "parent")
+	md := gotex.ContextMD{}
+	Inject(ctx, md)
+	traceParent := md.Get(strings.ToLower(gotex.RequestHeaderKeyTraceParent))
+	if !strings.Contains(traceParent, TraceID(ctx)) {
+		t.Fatalf("traceparent %q should carry trace id %s", traceParent, TraceID(ctx))
+	}
+
+	remoteCtx := Extract(context.Background(), md)
+	_, child := Start(remoteCtx, "child")
+	End(child, nil)
+	End(parent, nil)
+
+	type exportedSpan struct {
+		Name        string
+		SpanContext struct{ TraceID string }
+		Parent      struct{ SpanID string }
+	}
+	var spans []exportedSpan
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var s exportedSpan
+		if err = dec.Decode(&s); err != nil {
+			t.Fatalf("exported span should be json: %v", err)
+		}
+		spans = append(spans, s)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("should export 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "child" || spans[0].SpanContext.TraceID != TraceID(ctx) {
+		t.Errorf("child should share the parent trace, got %+v", spans[0])
+	}
+	if spans[0].Parent.SpanID != parent.SpanContext().SpanID().String() {
+		t.Errorf("child parent should be %s, got %s", parent.SpanContext().SpanID(), spans[0].Parent.SpanID)
+	}
+}
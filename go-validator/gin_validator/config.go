@@ -0,0 +1,27 @@
+package gin_validator
+
+type Config struct {
+	skipMethods map[string]bool
+}
+
+type ConfigFunc func(c *Config)
+
+// SkipMethods opts routes, e.g. "[POST] /v1/orders", out of validation. The
+// request is still bound.
+func SkipMethods(methods ...string) ConfigFunc {
+	return func(c *Config) {
+		for _, m := range methods {
+			c.skipMethods[m] = true
+		}
+	}
+}
+
+func generate(args ...ConfigFunc) *Config {
+	c := &Config{
+		skipMethods: make(map[string]bool),
+	}
+	for i := range args {
+		args[i](c)
+	}
+	return c
+}
@@ -0,0 +1,48 @@
+package gin_validator
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	govalidator "pkg.tanyudii.me/go-pkg/go-validator"
+)
+
+const (
+	ContextKeyRequest = "govalidator.request"
+)
+
+// Bind binds the request of a route into a new T and validates it before
+// the handler runs. The handler reads it back with GetRequest.
+//
+//	router.POST("/v1/orders", gin_validator.Bind[CreateOrderRequest](v), createOrder)
+func Bind[T any](v govalidator.Service, args ...ConfigFunc) func(c *gin.Context) {
+	cfg := generate(args...)
+	return func(c *gin.Context) {
+		req := new(T)
+		if err := c.ShouldBind(req); err != nil {
+			_ = c.Error(goerr.NewBadRequestError(err.Error()))
+			c.Abort()
+			return
+		}
+
+		method := fmt.Sprintf("[%s] %s", c.Request.Method, c.FullPath())
+		if !cfg.skipMethods[method] {
+			if err := govalidator.ValidateRequest(c.Request.Context(), v, req); err != nil {
+				_ = c.Error(err)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set(ContextKeyRequest, req)
+		c.Next()
+	}
+}
+
+// GetRequest returns the request bound by Bind, or nil if the route has no
+// Bind of type T.
+func GetRequest[T any](c *gin.Context) *T {
+	req, _ := c.Get(ContextKeyRequest)
+	r, _ := req.(*T)
+	return r
+}
@@ -0,0 +1,27 @@
+package grpc_validator
+
+type Config struct {
+	skipMethods map[string]bool
+}
+
+type ConfigFunc func(c *Config)
+
+// SkipMethods opts full gRPC methods, e.g. "/order.Service/Create", out of
+// validation.
+func SkipMethods(methods ...string) ConfigFunc {
+	return func(c *Config) {
+		for _, m := range methods {
+			c.skipMethods[m] = true
+		}
+	}
+}
+
+func generate(args ...ConfigFunc) *Config {
+	c := &Config{
+		skipMethods: make(map[string]bool),
+	}
+	for i := range args {
+		args[i](c)
+	}
+	return c
+}
@@ -0,0 +1,21 @@
+package grpc_validator
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	govalidator "pkg.tanyudii.me/go-pkg/go-validator"
+)
+
+// UnaryInterceptor validates every request before it reaches the handler.
+// It should run after the interceptor placing Accept-Language in gotex.
+func UnaryInterceptor(v govalidator.Service, args ...ConfigFunc) grpc.UnaryServerInterceptor {
+	cfg := generate(args...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !cfg.skipMethods[info.FullMethod] {
+			if err := govalidator.ValidateRequest(ctx, v, req); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,72 @@
+package go_validator
+
+import (
+	"context"
+	"errors"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+)
+
+// selfValidator is implemented by messages carrying their own rules, e.g.
+// those generated by protoc-gen-validate.
+type selfValidator interface {
+	Validate() error
+}
+
+type selfAllValidator interface {
+	ValidateAll() error
+}
+
+type fieldError interface {
+	Field() string
+	Reason() string
+}
+
+type multiError interface {
+	AllErrors() []error
+}
+
+// ValidateRequest validates req using its own ValidateAll or Validate
+// method when it has one, and the struct tags through v otherwise. With a
+// nil v only requests with their own rules are validated. Failures are
+// returned as a goerr BadRequest error with one field per violation.
+func ValidateRequest(ctx context.Context, v Service, req interface{}) error {
+	if req == nil {
+		return nil
+	}
+	switch r := req.(type) {
+	case selfAllValidator:
+		return toBadRequest(r.ValidateAll())
+	case selfValidator:
+		return toBadRequest(r.Validate())
+	}
+	if v == nil {
+		return nil
+	}
+	return v.StructWithCtx(ctx, req)
+}
+
+func toBadRequest(err error) error {
+	if err == nil {
+		return nil
+	}
+	var custom goerr.CustomError
+	if errors.As(err, &custom) {
+		return err
+	}
+
+	errs := []error{err}
+	if m, ok := err.(multiError); ok {
+		errs = m.AllErrors()
+	}
+	fields := make(goerr.ErrorField)
+	for _, e := range errs {
+		var fe fieldError
+		if errors.As(e, &fe) {
+			fields[fe.Field()] = fe.Reason()
+		}
+	}
+	if len(fields) == 0 {
+		return goerr.NewBadRequestError(err.Error())
+	}
+	return goerr.NewBadRequestErrorUsingFieldsOrNil(fields)
+}
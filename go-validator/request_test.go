@@ -0,0 +1,66 @@
+package go_validator
+
+import (
+	"context"
+	"errors"
+	goerr "pkg.tanyudii.me/go-pkg/go-err"
+	"testing"
+)
+
+type testFieldError struct {
+	field  string
+	reason string
+}
+
+func (e testFieldError) Error() string  { return e.field + ": " + e.reason }
+func (e testFieldError) Field() string  { return e.field }
+func (e testFieldError) Reason() string { return e.reason }
+
+type testMultiError []error
+
+func (m testMultiError) Error() string      { return m[0].Error() }
+func (m testMultiError) AllErrors() []error { return m }
+
+type testSelfValidatedRequest struct {
+	err error
+}
+
+func (r *testSelfValidatedRequest) ValidateAll() error {
+	return r.err
+}
+
+func TestValidateRequest(t *testing.T) {
+	v := NewValidator()
+	ctx := context.Background()
+
+	err := ValidateRequest(ctx, v, &testSelfValidatedRequest{err: testMultiError{
+		testFieldError{field: "name", reason: "value length must be at least 1 runes"},
+		testFieldError{field: "email", reason: "value must be a valid email address"},
+	}})
+	var custom goerr.CustomError
+	if !goerr.IsBadRequestError(err) || !errors.As(err, &custom) {
+		t.Fatalf("error should be BadRequestError, got %v", err)
+	}
+	if fields := custom.GetFields(); len(fields) != 2 || fields["email"] != "value must be a valid email address" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	if err = ValidateRequest(ctx, v, &testSelfValidatedRequest{}); err != nil {
+		t.Errorf("valid request should pass, got %v", err)
+	}
+
+	type taggedRequest struct {
+		Name string `validate:"required"`
+	}
+	if err = ValidateRequest(ctx, v, &taggedRequest{}); !goerr.IsBadRequestError(err) {
+		t.Errorf("request without own rules should fall back to struct tags, got %v", err)
+	}
+
+	if err = ValidateRequest(ctx, nil, &taggedRequest{}); err != nil {
+		t.Errorf("without a service struct tags should be skipped, got %v", err)
+	}
+	err = ValidateRequest(ctx, nil, &testSelfValidatedRequest{err: testFieldError{field: "name", reason: "required"}})
+	if !goerr.IsBadRequestError(err) {
+		t.Errorf("without a service own rules should still run, got %v", err)
+	}
+}